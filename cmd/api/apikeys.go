@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+// apiKeyTracker buffers the last time each API key was used, so that requests
+// authenticated with a key don't each write to the api_keys table.
+type apiKeyTracker struct {
+	mu   sync.Mutex
+	used map[int64]time.Time
+}
+
+func newAPIKeyTracker() *apiKeyTracker {
+	return &apiKeyTracker{used: make(map[int64]time.Time)}
+}
+
+func (t *apiKeyTracker) use(id int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.used[id] = time.Now()
+}
+
+func (t *apiKeyTracker) drain() map[int64]time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	used := t.used
+	t.used = make(map[int64]time.Time)
+	return used
+}
+
+func (t *apiKeyTracker) restore(used map[int64]time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, usedAt := range used {
+		if usedAt.After(t.used[id]) {
+			t.used[id] = usedAt
+		}
+	}
+}
+
+func (s *app) flushAPIKeys() error {
+	used := s.apiKeys.drain()
+
+	err := s.models.APIKeys.Touch(used)
+	if err != nil {
+		s.apiKeys.restore(used)
+		return err
+	}
+	return nil
+}
+
+// authenticateAPIKey writes the error response itself and reports whether
+// the request may proceed.
+func (s *app) authenticateAPIKey(w http.ResponseWriter, r *http.Request, token string) (*http.Request, bool) {
+	key, user, err := s.models.APIKeys.GetForKey(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.invalidAuthenticationTokenResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return r, false
+	}
+
+	if !key.AllowsIP(s.clientIP(r)) {
+		s.invalidAuthenticationTokenResponse(w, r)
+		return r, false
+	}
+
+	s.apiKeys.use(key.ID)
+
+	user.Permissions = key.Permissions
+
+	return s.contextSetDelegated(s.contextSetUser(r, user)), true
+}
+
+func (s *app) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name        string     `json:"name"`
+		Permissions []string   `json:"permissions"`
+		AllowedIPs  []string   `json:"allowed_ips"`
+		Expiry      *time.Time `json:"expiry"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	granted, err := s.userPermissions(user)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	key := &data.APIKey{
+		UserID:      user.ID,
+		Name:        input.Name,
+		Permissions: input.Permissions,
+		AllowedIPs:  input.AllowedIPs,
+		Expiry:      input.Expiry,
+	}
+
+	v := validator.New()
+	if data.ValidateAPIKey(v, key, granted); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.APIKeys.Insert(key)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", "/api/v1/users/me/api-keys")
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"api_key": key}, headers)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.models.APIKeys.GetAllForUser(s.contextGetUser(r).ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"api_keys": keys}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) deleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	err = s.models.APIKeys.Delete(id, s.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "api key successfully revoked"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+const (
+	maxNominationsBytes = 5 * 1_048_576
+	maxNominationsLines = 5000
+)
+
+func (s *app) createAwardEventHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name   string     `json:"name"`
+		Year   int32      `json:"year"`
+		HeldOn *data.Date `json:"held_on"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	event := &data.AwardEvent{
+		Name:   input.Name,
+		Year:   input.Year,
+		HeldOn: input.HeldOn,
+	}
+
+	v := validator.New()
+	if data.ValidateAwardEvent(v, event); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.Awards.InsertEvent(event)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateName):
+			v.AddError("name", "an event with this name already exists for this year")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/awards/%d", event.ID))
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"award_event": event}, headers)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) showAwardEventHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	event, err := s.models.Awards.GetEvent(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"award_event": event}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) deleteAwardEventHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	err = s.models.Awards.DeleteEvent(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "award event successfully deleted"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) listAwardEventsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string
+		Year int
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Name = s.readString(qs, "name", "")
+	input.Year = s.readInt(qs, "year", 0, v)
+	input.Filters.Page = s.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = s.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = s.readString(qs, "sort", "-year")
+	input.Filters.SortSafelist = []string{"id", "name", "year", "-id", "-name", "-year"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	events, metadata, err := s.models.Awards.GetAllEvents(input.Name, input.Year, input.Filters)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"award_events": events, "metadata": metadata}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) listMovieAwardsHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	nominations, err := s.models.Awards.GetAllForMovie(movie.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"nominations": nominations}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// importNominationsHandler reads a CSV file with a header line naming the
+// category, movie_id, nominee and won columns, the last two being optional.
+// Nothing is imported unless every line is valid.
+func (s *app) importNominationsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	event, err := s.models.Awards.GetEvent(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	reader := csv.NewReader(http.MaxBytesReader(w, r.Body, maxNominationsBytes))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	v := validator.New()
+
+	header, err := reader.Read()
+	if err != nil {
+		s.badRequestResponse(w, r, fmt.Errorf("body must be a CSV file with a header line: %w", err))
+		return
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	for _, required := range []string{"category", "movie_id"} {
+		_, ok := columns[required]
+		v.Check(ok, "line 1", "header must name a "+required+" column")
+	}
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var (
+		nominations []*data.Nomination
+		lines       = make(map[int64]int)
+		movieIDs    []int64
+	)
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseError *csv.ParseError
+			if errors.As(err, &parseError) {
+				v.AddError(fmt.Sprintf("line %d", parseError.Line), parseError.Err.Error())
+				continue
+			}
+			s.badRequestResponse(w, r, err)
+			return
+		}
+
+		line, _ := reader.FieldPos(0)
+		key := fmt.Sprintf("line %d", line)
+
+		if len(nominations) == maxNominationsLines {
+			v.AddError(key, fmt.Sprintf("file must not contain more than %d nominations", maxNominationsLines))
+			break
+		}
+
+		nomination := &data.Nomination{
+			Category: field(record, "category"),
+			Nominee:  field(record, "nominee"),
+		}
+
+		nomination.MovieID, err = strconv.ParseInt(field(record, "movie_id"), 10, 64)
+		if err != nil {
+			v.AddError(key, "movie_id must be an integer")
+		}
+
+		if won := field(record, "won"); won != "" {
+			nomination.Won, err = strconv.ParseBool(won)
+			if err != nil {
+				v.AddError(key, "won must be true or false")
+			}
+		}
+
+		data.ValidateNomination(v, key, nomination)
+
+		if _, seen := lines[nomination.MovieID]; !seen && nomination.MovieID > 0 {
+			lines[nomination.MovieID] = line
+			movieIDs = append(movieIDs, nomination.MovieID)
+		}
+		nominations = append(nominations, nomination)
+	}
+
+	if len(nominations) == 0 && v.Valid() {
+		v.AddError("line 2", "file does not contain any nominations")
+	}
+
+	if len(movieIDs) > 0 {
+		missing, err := s.models.Awards.MissingMovies(movieIDs)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+		for _, movieID := range missing {
+			v.AddError(fmt.Sprintf("line %d", lines[movieID]), fmt.Sprintf("movie %d does not exist", movieID))
+		}
+	}
+
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	start := time.Now()
+
+	err = s.models.Awards.ImportNominations(event.ID, nominations)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	s.logger.PrintInfo("imported nominations", map[string]string{
+		"event":    strconv.FormatInt(event.ID, 10),
+		"count":    strconv.Itoa(len(nominations)),
+		"duration": time.Since(start).String(),
+	})
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"imported": len(nominations)}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *app) showBoxOfficeHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	currency := s.readCurrency(r.URL.Query(), v)
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	boxOffice, err := s.models.BoxOffice.Get(movie.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	rates, err := s.models.ExchangeRates.Rates()
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if _, err := rates.Convert(data.Money{Currency: currency}, data.ReportingCurrency); err != nil {
+		v.AddError("currency", "no exchange rate is available for "+currency)
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = boxOffice.Total(rates, currency)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"box_office": boxOffice}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) updateBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	var budget data.Money
+
+	err := s.readJSON(w, r, &budget)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateMoney(v, "currency", budget); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.BoxOffice.SetBudget(movie.ID, &budget)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"budget": budget}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) deleteBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.models.BoxOffice.SetBudget(movie.ID, nil)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "budget successfully deleted"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) updateGrossHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	gross := &data.Gross{Region: strings.ToUpper(chi.URLParam(r, "region"))}
+
+	err := s.readJSON(w, r, &gross.Gross)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(validator.Matches(gross.Region, data.CountryRX), "region", "must be an ISO 3166-1 alpha-2 code such as ID or US")
+
+	if data.ValidateMoney(v, "currency", gross.Gross); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.BoxOffice.UpsertGross(movie.ID, gross)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"gross": gross}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) deleteGrossHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.models.BoxOffice.DeleteGross(movie.ID, strings.ToUpper(chi.URLParam(r, "region")))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "gross successfully deleted"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) boxOfficeLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Year     int
+		Currency string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Year = s.readInt(qs, "year", time.Now().Year(), v)
+	input.Currency = s.readCurrency(qs, v)
+	input.Filters.Page = s.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = s.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = "-gross"
+	input.Filters.SortSafelist = []string{"-gross"}
+
+	v.Check(input.Year >= 1888, "year", "must be greater than 1888")
+	v.Check(input.Year <= time.Now().Year(), "year", "must not be in the future")
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	rates, err := s.models.ExchangeRates.Rates()
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if _, err := rates.Convert(data.Money{Currency: input.Currency}, data.ReportingCurrency); err != nil {
+		v.AddError("currency", "no exchange rate is available for "+input.Currency)
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	entries, metadata, err := s.models.BoxOffice.Leaderboard(input.Year, input.Filters)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, entry := range entries {
+		entry.Gross, err = rates.Convert(entry.Gross, input.Currency)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"leaderboard": entries, "metadata": metadata}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) listExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	rates, err := s.models.ExchangeRates.GetAll()
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"reporting_currency": data.ReportingCurrency, "exchange_rates": rates}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) updateExchangeRateHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Rate json.Number `json:"rate"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	rate := &data.ExchangeRate{
+		Currency: strings.ToUpper(chi.URLParam(r, "currency")),
+		Rate:     input.Rate.String(),
+	}
+
+	v := validator.New()
+	if data.ValidateExchangeRate(v, rate); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.ExchangeRates.Upsert(rate)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"exchange_rate": rate}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
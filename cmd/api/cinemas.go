@@ -0,0 +1,329 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+func (s *app) createCinemaHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name     string `json:"name"`
+		Address  string `json:"address"`
+		City     string `json:"city"`
+		Country  string `json:"country"`
+		Timezone string `json:"timezone"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	cinema := &data.Cinema{
+		Name:     input.Name,
+		Address:  input.Address,
+		City:     input.City,
+		Country:  strings.ToUpper(input.Country),
+		Timezone: input.Timezone,
+	}
+
+	v := validator.New()
+	if data.ValidateCinema(v, cinema); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.Cinemas.Insert(cinema)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUnknownTimezone):
+			v.AddError("timezone", "must be an IANA time zone such as Asia/Jakarta")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/cinemas/%d", cinema.ID))
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"cinema": cinema}, headers)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) showCinemaHandler(w http.ResponseWriter, r *http.Request) {
+	cinema, ok := s.readCinema(w, r)
+	if !ok {
+		return
+	}
+
+	var err error
+	cinema.Screens, err = s.models.Screens.GetAllForCinema(cinema.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"cinema": cinema}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) updateCinemaHandler(w http.ResponseWriter, r *http.Request) {
+	cinema, ok := s.readCinema(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name     *string `json:"name"`
+		Address  *string `json:"address"`
+		City     *string `json:"city"`
+		Country  *string `json:"country"`
+		Timezone *string `json:"timezone"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		cinema.Name = *input.Name
+	}
+
+	if input.Address != nil {
+		cinema.Address = *input.Address
+	}
+
+	if input.City != nil {
+		cinema.City = *input.City
+	}
+
+	if input.Country != nil {
+		cinema.Country = strings.ToUpper(*input.Country)
+	}
+
+	if input.Timezone != nil {
+		cinema.Timezone = *input.Timezone
+	}
+
+	v := validator.New()
+
+	if data.ValidateCinema(v, cinema); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.Cinemas.Update(cinema)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUnknownTimezone):
+			v.AddError("timezone", "must be an IANA time zone such as Asia/Jakarta")
+			s.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			s.editConclictResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"cinema": cinema}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) deleteCinemaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	err = s.models.Cinemas.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "cinema successfully deleted"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) listCinemasHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name    string
+		City    string
+		Country string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Name = s.readString(qs, "name", "")
+	input.City = s.readString(qs, "city", "")
+	input.Country = strings.ToUpper(s.readString(qs, "country", ""))
+	input.Filters.Page = s.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = s.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = s.readString(qs, "sort", "id")
+	input.Filters.SortSafelist = []string{"id", "name", "city", "-id", "-name", "-city"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	cinemas, metadata, err := s.models.Cinemas.GetAll(input.Name, input.City, input.Country, input.Filters)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"cinemas": cinemas, "metadata": metadata}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// readCinema looks up the cinema addressed by the {id} URL parameter, writing
+// the error response itself when it cannot be found.
+func (s *app) readCinema(w http.ResponseWriter, r *http.Request) (*data.Cinema, bool) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	cinema, err := s.models.Cinemas.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return cinema, true
+}
+
+func (s *app) createScreenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name    string       `json:"name"`
+		SeatMap data.SeatMap `json:"seat_map"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	cinema, ok := s.readCinema(w, r)
+	if !ok {
+		return
+	}
+
+	screen := &data.Screen{
+		CinemaID: cinema.ID,
+		Name:     input.Name,
+		SeatMap:  input.SeatMap,
+	}
+
+	v := validator.New()
+	if data.ValidateScreen(v, screen); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.Screens.Insert(screen)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateName):
+			v.AddError("name", "a screen with this name already exists in this cinema")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/screens/%d", screen.ID))
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"screen": screen}, headers)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) showScreenHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	screen, err := s.models.Screens.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"screen": screen}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) deleteScreenHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	err = s.models.Screens.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "screen successfully deleted"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
@@ -9,7 +9,12 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey      = contextKey("user")
+	tokenContextKey     = contextKey("token")
+	sessionContextKey   = contextKey("session")
+	delegatedContextKey = contextKey("delegated")
+)
 
 func (s *app) contextSetUser(r *http.Request, user *data.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
@@ -23,3 +28,41 @@ func (s *app) contextGetUser(r *http.Request) *data.User {
 	}
 	return user
 }
+
+// contextSetToken stores the hash of the bearer token the request was
+// authenticated with, so that it can be revoked on logout.
+func (s *app) contextSetToken(r *http.Request, hash []byte) *http.Request {
+	ctx := context.WithValue(r.Context(), tokenContextKey, hash)
+	return r.WithContext(ctx)
+}
+
+// contextGetToken returns nil when the request was authenticated with a
+// signed access token, which has no stored hash.
+func (s *app) contextGetToken(r *http.Request) []byte {
+	hash, _ := r.Context().Value(tokenContextKey).([]byte)
+	return hash
+}
+
+// contextSetSession stores the token family of a signed access token, which
+// is what has to be revoked on logout.
+func (s *app) contextSetSession(r *http.Request, family []byte) *http.Request {
+	ctx := context.WithValue(r.Context(), sessionContextKey, family)
+	return r.WithContext(ctx)
+}
+
+func (s *app) contextGetSession(r *http.Request) []byte {
+	family, _ := r.Context().Value(sessionContextKey).([]byte)
+	return family
+}
+
+// contextSetDelegated marks the request as made with an API key or a token
+// issued to an OAuth client, rather than by the user signing in.
+func (s *app) contextSetDelegated(r *http.Request) *http.Request {
+	ctx := context.WithValue(r.Context(), delegatedContextKey, true)
+	return r.WithContext(ctx)
+}
+
+func (s *app) contextIsDelegated(r *http.Request) bool {
+	delegated, _ := r.Context().Value(delegatedContextKey).(bool)
+	return delegated
+}
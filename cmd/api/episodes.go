@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+func (s *app) createEpisodeHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Number  int32        `json:"number"`
+		Title   string       `json:"title"`
+		Runtime data.Runtime `json:"runtime"`
+		AirDate *data.Date   `json:"air_date"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	season, ok := s.readSeason(w, r)
+	if !ok {
+		return
+	}
+
+	episode := &data.Episode{
+		SeasonID: season.ID,
+		Number:   input.Number,
+		Title:    input.Title,
+		Runtime:  input.Runtime,
+		AirDate:  input.AirDate,
+	}
+
+	v := validator.New()
+	episode.RuntimeFormat = s.readRuntimeFormat(r.URL.Query(), v)
+
+	if data.ValidateEpisode(v, episode); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.Episodes.Insert(episode)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateNumber):
+			v.AddError("number", "an episode with this number already exists")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/series/%d/seasons/%d/episodes/%d", season.SeriesID, season.Number, episode.Number))
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"episode": episode}, headers)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) listEpisodesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	format := s.readRuntimeFormat(r.URL.Query(), v)
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	season, ok := s.readSeason(w, r)
+	if !ok {
+		return
+	}
+
+	episodes, err := s.models.Episodes.GetAllForSeason(season.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, episode := range episodes {
+		episode.RuntimeFormat = format
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"episodes": episodes}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// readEpisode looks up the episode addressed by the {id}, {season} and
+// {episode} URL parameters, writing the error response itself when it cannot
+// be found.
+func (s *app) readEpisode(w http.ResponseWriter, r *http.Request) (*data.Episode, bool) {
+	season, ok := s.readSeason(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	number, err := s.readNumberParam(r, "episode")
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	episode, err := s.models.Episodes.Get(season.ID, number)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return episode, true
+}
+
+func (s *app) showEpisodeHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	format := s.readRuntimeFormat(r.URL.Query(), v)
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	episode, ok := s.readEpisode(w, r)
+	if !ok {
+		return
+	}
+
+	episode.RuntimeFormat = format
+
+	err := s.writeJSON(w, http.StatusOK, envelope{"episode": episode}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) updateEpisodeHandler(w http.ResponseWriter, r *http.Request) {
+	episode, ok := s.readEpisode(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Number  *int32        `json:"number"`
+		Title   *string       `json:"title"`
+		Runtime *data.Runtime `json:"runtime"`
+		AirDate *data.Date    `json:"air_date"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Number != nil {
+		episode.Number = *input.Number
+	}
+
+	if input.Title != nil {
+		episode.Title = *input.Title
+	}
+
+	if input.Runtime != nil {
+		episode.Runtime = *input.Runtime
+	}
+
+	if input.AirDate != nil {
+		episode.AirDate = input.AirDate
+	}
+
+	v := validator.New()
+	episode.RuntimeFormat = s.readRuntimeFormat(r.URL.Query(), v)
+
+	if data.ValidateEpisode(v, episode); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.Episodes.Update(episode)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateNumber):
+			v.AddError("number", "an episode with this number already exists")
+			s.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			s.editConclictResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"episode": episode}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) deleteEpisodeHandler(w http.ResponseWriter, r *http.Request) {
+	episode, ok := s.readEpisode(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.models.Episodes.Delete(episode.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "episode successfully deleted"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func (s *app) logError(r *http.Request, err error) {
@@ -50,11 +53,46 @@ func (s *app) editConclictResponse(w http.ResponseWriter, r *http.Request) {
 	s.errorResponse(w, r, http.StatusConflict, message)
 }
 
+func (s *app) duplicateMovieResponse(w http.ResponseWriter, r *http.Request, ids []int64) {
+	message := envelope{
+		"message":    "a movie with the same title and year already exists, use ?force=true to create it anyway",
+		"duplicates": ids,
+	}
+	s.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (s *app) seatsTakenResponse(w http.ResponseWriter, r *http.Request, seats []string) {
+	message := envelope{
+		"message": "some of the requested seats are no longer available",
+		"seats":   seats,
+	}
+	s.errorResponse(w, r, http.StatusConflict, message)
+}
+
+func (s *app) holdExpiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the hold on these seats has expired, please hold them again"
+	s.errorResponse(w, r, http.StatusConflict, message)
+}
+
 func (s *app) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rate limit exceeded"
 	s.errorResponse(w, r, http.StatusTooManyRequests, message)
 }
 
+func (s *app) activationCooldownResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+	message := "an activation token was sent recently, please wait before requesting another one"
+	s.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
+func (s *app) loginThrottledResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+
+	message := "too many failed sign in attempts, please try again later"
+	s.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
+
 func (s *app) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
 	message := "invalid authentication creadentials"
 	s.errorResponse(w, r, http.StatusUnauthorized, message)
@@ -67,6 +105,16 @@ func (s *app) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.
 	s.errorResponse(w, r, http.StatusUnauthorized, message)
 }
 
+func (s *app) invalidRefreshTokenResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid or expired refresh token"
+	s.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (s *app) refreshTokenReusedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "refresh token has already been used, please sign in again"
+	s.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
 func (s *app) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
 	message := "you must be authenticated to access this resouce"
 	s.errorResponse(w, r, http.StatusUnauthorized, message)
@@ -81,3 +129,8 @@ func (s *app) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
 	message := "your user account doesn't have the necessary permissions to access this resource"
 	s.errorResponse(w, r, http.StatusForbidden, message)
 }
+
+func (s *app) delegatedCredentialsResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this resource cannot be accessed with an API key or an OAuth client token, sign in with your password instead"
+	s.errorResponse(w, r, http.StatusForbidden, message)
+}
@@ -5,11 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/SemmiDev/chimovies/internal/data"
 	"github.com/SemmiDev/chimovies/internal/validator"
 	"github.com/go-chi/chi/v5"
 )
@@ -22,6 +25,14 @@ func (s *app) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+func (s *app) readNumberParam(r *http.Request, key string) (int32, error) {
+	n, err := strconv.ParseInt(chi.URLParam(r, key), 10, 32)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s parameter", key)
+	}
+	return int32(n), nil
+}
+
 type envelope map[string]interface{}
 
 func (s *app) writeJSON(w http.ResponseWriter, status int, data envelope, header http.Header) error {
@@ -120,6 +131,51 @@ func (s *app) readInt(qs url.Values, key string, defaultValue int, v *validator.
 	return i
 }
 
+func (s *app) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	rb := qs.Get(key)
+
+	if rb == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(rb)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+	return b
+}
+
+func (s *app) readDate(qs url.Values, key string, defaultValue time.Time, v *validator.Validator) time.Time {
+	rd := qs.Get(key)
+
+	if rd == "" {
+		return defaultValue
+	}
+
+	d, err := time.Parse("2006-01-02", rd)
+	if err != nil {
+		v.AddError(key, "must be a date in YYYY-MM-DD format")
+		return defaultValue
+	}
+	return d
+}
+
+func (s *app) readCurrency(qs url.Values, v *validator.Validator) string {
+	currency := strings.ToUpper(s.readString(qs, "currency", data.ReportingCurrency))
+	data.ValidateCurrency(v, "currency", currency)
+	return currency
+}
+
+func (s *app) readRuntimeFormat(qs url.Values, v *validator.Validator) data.RuntimeFormat {
+	format := s.readString(qs, "runtime_format", "")
+
+	if format != "" && !validator.In(format, data.RuntimeFormats...) {
+		v.AddError("runtime_format", "must be one of minutes, human or iso8601")
+	}
+	return data.RuntimeFormat(format)
+}
+
 func (s *app) background(fn func()) {
 	go func() {
 		defer func() {
@@ -130,3 +186,54 @@ func (s *app) background(fn func()) {
 		fn()
 	}()
 }
+
+// clientIP returns the address of the client. Forwarding headers are only
+// believed when the connection comes from a trusted proxy, and then
+// X-Forwarded-For is read from the right, past any further trusted proxies,
+// so that a client cannot claim someone else's address.
+func (s *app) clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	if !s.trustedProxy(ip) {
+		return ip
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	if len(forwarded) == 1 && strings.TrimSpace(forwarded[0]) == "" {
+		if realIP := strings.TrimSpace(r.Header.Get("X-Real-Ip")); net.ParseIP(realIP) != nil {
+			return realIP
+		}
+		return ip
+	}
+
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+
+		ip = hop
+		if !s.trustedProxy(ip) {
+			break
+		}
+	}
+
+	return ip
+}
+
+func (s *app) trustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, proxy := range s.proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
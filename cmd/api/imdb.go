@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/imdb"
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+type importCheckpoint struct {
+	Basics string `json:"basics"`
+	Line   int    `json:"line"`
+}
+
+func (s *app) importIMDb(args []string) error {
+	fs := flag.NewFlagSet("import-imdb", flag.ContinueOnError)
+	basicsPath := fs.String("basics", "title.basics.tsv.gz", "path to title.basics.tsv.gz")
+	ratingsPath := fs.String("ratings", "title.ratings.tsv.gz", "path to title.ratings.tsv.gz")
+	batchSize := fs.Int("batch-size", 5000, "number of movies upserted per statement")
+	checkpointPath := fs.String("checkpoint", "imdb-import.checkpoint", "file recording import progress")
+	resume := fs.Bool("resume", true, "continue from the checkpoint file if it exists")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *batchSize < 1 {
+		return errors.New("batch-size must be greater than zero")
+	}
+
+	checkpoint := importCheckpoint{Basics: *basicsPath}
+	if *resume {
+		checkpoint, err = readImportCheckpoint(*checkpointPath, *basicsPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.logger.PrintInfo("loading imdb ratings", map[string]string{"file": *ratingsPath})
+
+	ratings, err := imdb.ReadRatings(*ratingsPath)
+	if err != nil {
+		return err
+	}
+
+	basics, err := imdb.OpenBasics(*basicsPath)
+	if err != nil {
+		return err
+	}
+	defer basics.Close()
+
+	if checkpoint.Line > 0 {
+		s.logger.PrintInfo("resuming imdb import", map[string]string{"line": strconv.Itoa(checkpoint.Line)})
+
+		err = basics.Skip(checkpoint.Line)
+		if err != nil {
+			return err
+		}
+	}
+
+	var (
+		start    = time.Now()
+		batch    = make([]*data.IMDbMovie, 0, *batchSize)
+		imported int64
+		skipped  int
+	)
+
+	flush := func() error {
+		n, err := s.models.Movies.UpsertIMDb(batch)
+		if err != nil {
+			return err
+		}
+		imported += n
+		batch = batch[:0]
+
+		checkpoint.Line = basics.Line()
+		err = writeImportCheckpoint(*checkpointPath, checkpoint)
+		if err != nil {
+			return err
+		}
+
+		s.logger.PrintInfo("imdb import progress", map[string]string{
+			"line":     strconv.Itoa(checkpoint.Line),
+			"upserted": strconv.FormatInt(imported, 10),
+			"skipped":  strconv.Itoa(skipped),
+			"elapsed":  time.Since(start).Round(time.Second).String(),
+		})
+		return nil
+	}
+
+	for {
+		title, err := basics.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+
+		movie := &data.Movie{
+			Title:   title.Title,
+			Year:    title.Year,
+			Runtime: data.Runtime(title.Runtime),
+			Genres:  title.Genres,
+		}
+
+		v := validator.New()
+		if data.ValidateMovie(v, movie); !v.Valid() {
+			skipped++
+			continue
+		}
+
+		rating := ratings[title.TConst]
+		batch = append(batch, &data.IMDbMovie{
+			IMDbID: title.TConst,
+			Movie:  movie,
+			Rating: rating.Average,
+			Votes:  rating.Votes,
+		})
+
+		if len(batch) == *batchSize {
+			err = flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = flush()
+	if err != nil {
+		return err
+	}
+
+	s.logger.PrintInfo("imdb import finished", map[string]string{
+		"upserted": strconv.FormatInt(imported, 10),
+		"skipped":  strconv.Itoa(skipped),
+		"elapsed":  time.Since(start).Round(time.Second).String(),
+	})
+
+	return os.Remove(*checkpointPath)
+}
+
+func readImportCheckpoint(path, basicsPath string) (importCheckpoint, error) {
+	checkpoint := importCheckpoint{Basics: basicsPath}
+
+	js, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return checkpoint, nil
+		}
+		return checkpoint, err
+	}
+
+	err = json.Unmarshal(js, &checkpoint)
+	if err != nil {
+		return checkpoint, fmt.Errorf("invalid checkpoint file %s: %w", path, err)
+	}
+
+	if checkpoint.Basics != basicsPath {
+		return checkpoint, fmt.Errorf("checkpoint file %s belongs to %s, run with -resume=false to start over", path, checkpoint.Basics)
+	}
+
+	return checkpoint, nil
+}
+
+func writeImportCheckpoint(path string, checkpoint importCheckpoint) error {
+	js, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, js, 0o644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/jwt"
+)
+
+const tokenFormatJWT = "jwt"
+
+const (
+	// jwksMaxAge is how long clients may cache the JWKS document.
+	jwksMaxAge = 5 * time.Minute
+
+	// signingKeyReload is how often every instance reloads the signing keys.
+	signingKeyReload = time.Minute
+
+	// signingKeyActivation is how long a new key is published before it signs
+	// tokens, so that other instances and cached JWKS documents know it.
+	signingKeyActivation = jwksMaxAge + signingKeyReload
+)
+
+// opaqueAccessTokenTTL is zero when access tokens are signed instead of stored,
+// so that no stored access token is issued alongside the refresh token.
+func (s *app) opaqueAccessTokenTTL() time.Duration {
+	if s.config.TokenFormat == tokenFormatJWT {
+		return 0
+	}
+	return s.config.AccessTokenTTL
+}
+
+func (s *app) signAccessToken(user *data.User, family []byte) (*data.Token, error) {
+	permissions, err := s.userPermissions(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if permissions == nil {
+		permissions = data.Permissions{}
+	}
+
+	now := time.Now()
+	expiry := now.Add(s.config.AccessTokenTTL)
+
+	token, err := s.keys.Sign(jwt.Claims{
+		Issuer:      s.config.JWTIssuer,
+		Subject:     strconv.FormatInt(user.ID, 10),
+		IssuedAt:    now.Unix(),
+		Expiry:      expiry.Unix(),
+		Activated:   user.Activated,
+		Permissions: permissions,
+		Session:     base64.RawURLEncoding.EncodeToString(family),
+		Client:      user.ClientID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.Token{Plaintext: token, UserID: user.ID, Expiry: expiry, Scope: data.ScopeAuthentication}, nil
+}
+
+// authenticateJWT verifies a signed access token and builds the user from its
+// claims alone, without touching the database.
+func (s *app) authenticateJWT(r *http.Request, token string) (*http.Request, bool) {
+	claims, err := s.keys.Verify(token, s.config.JWTIssuer)
+	if err != nil {
+		return r, false
+	}
+
+	id, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil || id < 1 {
+		return r, false
+	}
+
+	family, err := base64.RawURLEncoding.DecodeString(claims.Session)
+	if err != nil {
+		return r, false
+	}
+
+	permissions := data.Permissions(claims.Permissions)
+	if permissions == nil {
+		permissions = data.Permissions{}
+	}
+
+	user := &data.User{
+		ID:          id,
+		Activated:   claims.Activated,
+		Permissions: permissions,
+		ClientID:    claims.Client,
+	}
+
+	r = s.contextSetUser(r, user)
+	if user.ClientID != "" {
+		r = s.contextSetDelegated(r)
+	}
+	if len(family) > 0 {
+		r = s.contextSetSession(r, family)
+	}
+
+	return r, true
+}
+
+// rotateSigningKeys adds a new signing key once the newest one is older than
+// the rotation period, drops keys that can no longer have signed a live token
+// and reloads the key set, which also picks up keys other instances created.
+func (s *app) rotateSigningKeys() error {
+	stored, err := s.models.SigningKeys.GetAll()
+	if err != nil {
+		return err
+	}
+
+	if len(stored) == 0 || time.Since(stored[0].CreatedAt) >= s.config.JWTKeyRotation || stored[0].Algorithm != s.config.JWTAlgorithm {
+		key, err := jwt.GenerateKey(s.config.JWTAlgorithm)
+		if err != nil {
+			return err
+		}
+
+		der, err := key.MarshalPrivateKey()
+		if err != nil {
+			return err
+		}
+
+		signingKey := &data.SigningKey{ID: key.ID, Algorithm: key.Algorithm, PrivateKey: der}
+
+		err = s.models.SigningKeys.Insert(signingKey)
+		if err != nil {
+			return err
+		}
+
+		s.logger.PrintInfo("signing key created", map[string]string{"kid": key.ID, "alg": key.Algorithm})
+
+		stored = append([]*data.SigningKey{signingKey}, stored...)
+	}
+
+	// A retired key stops signing once its successor is activated, so it is
+	// needed for at most one more access token lifetime after that.
+	cutoff := time.Now().Add(-s.config.JWTKeyRotation - signingKeyActivation - s.config.AccessTokenTTL)
+
+	err = s.models.SigningKeys.DeleteRetired(cutoff)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*jwt.Key, 0, len(stored))
+	for i, signingKey := range stored {
+		if i > 0 && signingKey.CreatedAt.Before(cutoff) {
+			continue
+		}
+
+		key, err := jwt.ParseKey(signingKey.ID, signingKey.Algorithm, signingKey.PrivateKey, signingKey.CreatedAt)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	s.keys.Replace(keys)
+	return nil
+}
+
+func (s *app) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	headers := make(http.Header)
+	headers.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds())))
+
+	err := s.writeJSON(w, http.StatusOK, envelope{"keys": s.keys.PublicKeys()}, headers)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
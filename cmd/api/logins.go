@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+)
+
+// loginAttempt is a sign in attempt claimed against the email and the IP
+// address it was made for.
+type loginAttempt struct {
+	emailFailure *data.LoginFailure
+	ipFailure    *data.LoginFailure
+	ip           string
+}
+
+// emailLoginLimit backs off exponentially and locks the account after
+// LoginMaxFailures. ipLoginLimit only locks, at a higher threshold, so that
+// shared addresses are not slowed down by a single user's typos.
+func (s *app) emailLoginLimit() data.LoginLimit {
+	return data.LoginLimit{MaxFailures: s.config.LoginMaxFailures, Lockout: s.config.LoginLockout, Backoff: s.config.LoginBackoff}
+}
+
+func (s *app) ipLoginLimit() data.LoginLimit {
+	return data.LoginLimit{MaxFailures: s.config.LoginMaxFailuresPerIP, Lockout: s.config.LoginLockout}
+}
+
+// claimLoginAttempt counts the attempt as failed before the credentials are
+// checked. When either the email or the IP address may not try again yet, it
+// returns how long to wait and no attempt.
+func (s *app) claimLoginAttempt(r *http.Request, email string) (*loginAttempt, time.Duration, error) {
+	attempt := &loginAttempt{ip: s.clientIP(r)}
+
+	var (
+		wait time.Duration
+		err  error
+	)
+
+	attempt.ipFailure, wait, err = s.models.LoginFailures.Claim(data.LoginIPKey(attempt.ip), s.ipLoginLimit())
+	if err != nil || wait > 0 {
+		return nil, wait, err
+	}
+
+	attempt.emailFailure, wait, err = s.models.LoginFailures.Claim(data.LoginEmailKey(email), s.emailLoginLimit())
+	if err != nil || wait > 0 {
+		// The attempt does not happen, so the IP address gets its claim back.
+		releaseErr := s.models.LoginFailures.Release(attempt.ipFailure, s.ipLoginLimit())
+		if err == nil {
+			err = releaseErr
+		}
+		return nil, wait, err
+	}
+
+	return attempt, 0, nil
+}
+
+// releaseLoginAttempt gives back the claims of an attempt whose credentials
+// were correct.
+func (s *app) releaseLoginAttempt(attempt *loginAttempt) error {
+	err := s.models.LoginFailures.Release(attempt.emailFailure, s.emailLoginLimit())
+	if err != nil {
+		return err
+	}
+
+	return s.models.LoginFailures.Release(attempt.ipFailure, s.ipLoginLimit())
+}
+
+// loginAttemptFailed tells the account owner, when there is one, once their
+// account gets locked. The failure itself was counted when it was claimed.
+func (s *app) loginAttemptFailed(attempt *loginAttempt, user *data.User) {
+	failure := attempt.emailFailure
+
+	if user == nil || failure.Failures != s.config.LoginMaxFailures || failure.LockedUntil == nil {
+		return
+	}
+
+	lockedUntil := *failure.LockedUntil
+
+	s.background(func() {
+		data := map[string]interface{}{
+			"failures":    failure.Failures,
+			"ip":          attempt.ip,
+			"lockedUntil": lockedUntil.UTC().Format(time.RFC1123),
+		}
+
+		err := s.mailer.Send(user.Email, "account_locked.tmpl", data)
+		if err != nil {
+			s.logger.PrintError(err, nil)
+		}
+	})
+}
+
+func (s *app) pruneLoginFailures() error {
+	_, err := s.models.LoginFailures.Prune(time.Now().Add(-s.config.LoginLockout))
+	return err
+}
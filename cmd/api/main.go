@@ -4,21 +4,37 @@ import (
 	"context"
 	"database/sql"
 	"expvar"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/SemmiDev/chimovies/config"
+	"github.com/SemmiDev/chimovies/internal/blob"
 	"github.com/SemmiDev/chimovies/internal/data"
 	"github.com/SemmiDev/chimovies/internal/jsonlog"
+	"github.com/SemmiDev/chimovies/internal/jwt"
+	"github.com/SemmiDev/chimovies/internal/mailer"
+	"github.com/SemmiDev/chimovies/internal/videos"
 	_ "github.com/jackc/pgx/v4/stdlib"
 )
 
 type app struct {
-	config config.Config
-	logger *jsonlog.Logger
-	models data.Models
-	// mailer mailer.Mailer
+	config   config.Config
+	logger   *jsonlog.Logger
+	models   data.Models
+	similar  *similarCache
+	views    *viewCounter
+	sessions *sessionTracker
+	apiKeys  *apiKeyTracker
+	blobs    blob.Store
+	links    *videos.Checker
+	mailer   mailer.Mailer
+	keys     *jwt.KeySet
+	proxies  []*net.IPNet
 }
 
 func main() {
@@ -44,11 +60,60 @@ func main() {
 		return time.Now().Unix()
 	}))
 
+	blobs, err := blob.NewFileStore(config.BlobDir)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	proxies := make([]*net.IPNet, 0, len(config.TrustedProxies))
+	for _, entry := range config.TrustedProxies {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		proxy, err := data.ParseIPNet(entry)
+		if err != nil {
+			logger.PrintFatal(fmt.Errorf("invalid trusted proxy %q: %w", entry, err), nil)
+		}
+		proxies = append(proxies, proxy)
+	}
+
 	app := &app{
-		config: config,
-		logger: logger,
-		models: data.NewModels(db),
-		// mailer: mailer.New(config.SMTPHost, config.SMTPPort, config.Username, config.Password, config.Sender),
+		config:   config,
+		logger:   logger,
+		models:   data.NewModels(db),
+		similar:  newSimilarCache(config.SimilarCacheTTL, config.SimilarCacheSize),
+		views:    newViewCounter(),
+		sessions: newSessionTracker(),
+		apiKeys:  newAPIKeyTracker(),
+		blobs:    blobs,
+		links:    videos.NewChecker(&http.Client{Timeout: config.LinkCheckTimeout}),
+		mailer:   mailer.New(config.SMTPHost, config.SMTPPort, config.Username, config.Password, config.Sender),
+		keys:     jwt.NewKeySet(signingKeyActivation),
+		proxies:  proxies,
+	}
+
+	if len(os.Args) > 1 {
+		commands := map[string]func([]string) error{
+			"import-imdb":         app.importIMDb,
+			"create-oauth-client": app.createOAuthClient,
+			"delete-oauth-client": app.deleteOAuthClient,
+		}
+
+		if command, ok := commands[os.Args[1]]; ok {
+			err = command(os.Args[2:])
+			if err != nil {
+				logger.PrintFatal(err, nil)
+			}
+			return
+		}
+	}
+
+	if config.TokenFormat == tokenFormatJWT {
+		err = app.rotateSigningKeys()
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
 	}
 
 	err = app.serve()
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/totp"
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+const (
+	totpIssuer = "Chimovies"
+
+	mfaPendingTTL         = 5 * time.Minute
+	mfaPendingMaxAttempts = 5
+)
+
+// verifySecondFactor accepts either a current TOTP code, which cannot be used
+// twice, or an unused recovery code, which is spent.
+func (s *app) verifySecondFactor(t *data.TOTP, code string) (bool, error) {
+	if step, ok := totp.Validate(t.Secret, code, time.Now()); ok {
+		return s.models.MFA.UseStep(t.UserID, step)
+	}
+
+	if len(code) == totp.Digits {
+		return false, nil
+	}
+
+	return s.models.MFA.UseRecoveryCode(t.UserID, code)
+}
+
+func (s *app) createMFAAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"token"`
+		Code           string `json:"code"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidateTokenPlaintext(v, input.TokenPlaintext)
+	v.Check(input.Code != "", "code", "must be provided")
+
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := s.models.Users.GetForToken(data.ScopeMFAPending, input.TokenPlaintext)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired mfa token")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	attempt, wait, err := s.claimLoginAttempt(r, user.Email)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if wait > 0 {
+		s.loginThrottledResponse(w, r, wait)
+		return
+	}
+
+	t, err := s.models.MFA.Get(user.ID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Two-factor authentication may have been reset since the password step.
+	verified := t == nil || !t.Enabled()
+	if !verified {
+		verified, err = s.verifySecondFactor(t, input.Code)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if !verified {
+		_, err = s.models.Tokens.RecordFailedAttempt(data.HashToken(input.TokenPlaintext), mfaPendingMaxAttempts)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+
+		s.loginAttemptFailed(attempt, user)
+		s.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	err = s.releaseLoginAttempt(attempt)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.models.Tokens.DeleteAllForUser(data.ScopeMFAPending, user.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	s.issueAuthenticationTokens(w, r, user)
+}
+
+func (s *app) enrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	// The context user may have come from a signed token, which has no email.
+	user, err := s.models.Users.Get(s.contextGetUser(r).ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.models.MFA.Enroll(user.ID, secret)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrMFAEnabled):
+			v := validator.New()
+			v.AddError("totp", "two-factor authentication is already enabled")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	env := envelope{"totp": map[string]string{
+		"secret": secret,
+		"uri":    totp.URI(totpIssuer, user.Email, secret),
+	}}
+
+	err = s.writeJSON(w, http.StatusCreated, env, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) confirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	user := s.contextGetUser(r)
+
+	t, err := s.models.MFA.Get(user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("totp", "two-factor authentication must be enrolled first")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if t.Enabled() {
+		v.AddError("totp", "two-factor authentication is already enabled")
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	step, ok := totp.Validate(t.Secret, input.Code, time.Now())
+	if !ok {
+		v.AddError("code", "is not valid")
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	codes, err := data.GenerateRecoveryCodes()
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.models.MFA.Confirm(user.ID, step, codes)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrMFAEnabled):
+			v.AddError("totp", "two-factor authentication is already enabled")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"recovery_codes": codes}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) disableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	user := s.contextGetUser(r)
+
+	t, err := s.models.MFA.Get(user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if t.Enabled() {
+		// Codes are throttled like a sign in, so that a stolen session cannot
+		// guess its way past the second factor.
+		user, err = s.models.Users.Get(user.ID)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+
+		attempt, wait, err := s.claimLoginAttempt(r, user.Email)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if wait > 0 {
+			s.loginThrottledResponse(w, r, wait)
+			return
+		}
+
+		ok, err := s.verifySecondFactor(t, input.Code)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+		if !ok {
+			s.loginAttemptFailed(attempt, user)
+			v.AddError("code", "is not valid")
+			s.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = s.releaseLoginAttempt(attempt)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = s.models.MFA.Delete(user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "two-factor authentication successfully disabled"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// resetUserMFAHandler lets an admin turn off two-factor authentication for a
+// user who lost both their authenticator and their recovery codes.
+func (s *app) resetUserMFAHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	err = s.models.MFA.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.models.Tokens.DeleteAllForUser(data.ScopeMFAPending, id)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	s.logger.PrintInfo("two-factor authentication reset", map[string]string{
+		"user_id":  strconv.FormatInt(id, 10),
+		"admin_id": strconv.FormatInt(s.contextGetUser(r).ID, 10),
+	})
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "two-factor authentication successfully reset"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"golang.org/x/time/rate"
 
 	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/jwt"
 	"github.com/SemmiDev/chimovies/internal/validator"
 )
 
@@ -70,12 +71,40 @@ func (s *app) authenticate(next http.Handler) http.Handler {
 		}
 
 		headerParts := strings.Split(authorizationHeader, " ")
+
+		// Basic credentials authenticate OAuth clients, which is left to the
+		// token endpoint; everywhere else the request is anonymous.
+		if len(headerParts) == 2 && headerParts[0] == "Basic" {
+			r = s.contextSetUser(r, data.AnonymousUser)
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
 			s.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
 
 		token := headerParts[1]
+
+		if strings.HasPrefix(token, data.APIKeyPrefix) {
+			r, ok := s.authenticateAPIKey(w, r, token)
+			if ok {
+				next.ServeHTTP(w, r)
+			}
+			return
+		}
+
+		if s.config.TokenFormat == tokenFormatJWT && jwt.LooksLikeJWT(token) {
+			r, ok := s.authenticateJWT(r, token)
+			if !ok {
+				s.invalidAuthenticationTokenResponse(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		v := validator.New()
 		if data.ValidateTokenPlaintext(v, token); !v.Valid() {
 			s.invalidAuthenticationTokenResponse(w, r)
@@ -94,6 +123,14 @@ func (s *app) authenticate(next http.Handler) http.Handler {
 		}
 
 		r = s.contextSetUser(r, user)
+		if user.ClientID != "" {
+			r = s.contextSetDelegated(r)
+		}
+
+		hash := data.HashToken(token)
+		s.sessions.use(hash)
+
+		r = s.contextSetToken(r, hash)
 		next.ServeHTTP(w, r)
 	})
 }
@@ -121,43 +158,93 @@ func (s *app) requireActivatedUser(next http.HandlerFunc) http.HandlerFunc {
 	return s.requireAuthenticatedUser(fn)
 }
 
+// requireAccountOwner keeps API keys and OAuth client tokens away from the
+// routes that manage the account's credentials, so that a leaked key cannot
+// be turned into control of the account.
+func (s *app) requireAccountOwner(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.contextIsDelegated(r) {
+			s.delegatedCredentialsResponse(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 const (
-	permMoviesRead  = "movies:read"
-	permMoviesWrite = "movies:write"
+	permMoviesRead     = "movies:read"
+	permMoviesWrite    = "movies:write"
+	permMoviesAdmin    = "movies:admin"
+	permMoviesReview   = "movies:review"
+	permMoviesPublish  = "movies:publish"
+	permCinemasWrite   = "cinemas:write"
+	permBoxOfficeWrite = "boxoffice:write"
+	permAwardsWrite    = "awards:write"
+	permUsersAdmin     = "users:admin"
 )
 
-func (s *app) readPermMovies(next http.Handler) http.Handler {
-	fn := func(w http.ResponseWriter, r *http.Request) {
-		user := s.contextGetUser(r)
-		permissions, err := s.models.Permissions.GetAllForUser(user.ID)
-		if err != nil {
-			s.serverErrorResponse(w, r, err)
-			return
-		}
-		if !permissions.Include(permMoviesRead) {
-			s.notPermittedResponse(w, r)
-			return
+func (s *app) requirePermission(code string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			user := s.contextGetUser(r)
+			permissions, err := s.userPermissions(user)
+			if err != nil {
+				s.serverErrorResponse(w, r, err)
+				return
+			}
+			if !permissions.Include(code) {
+				s.notPermittedResponse(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
 		}
-		next.ServeHTTP(w, r)
+		return s.requireActivatedUser(fn)
 	}
-	return s.requireActivatedUser(fn)
 }
 
-func (s *app) writePermMovies(next http.Handler) http.Handler {
-	fn := func(w http.ResponseWriter, r *http.Request) {
-		user := s.contextGetUser(r)
-		permissions, err := s.models.Permissions.GetAllForUser(user.ID)
-		if err != nil {
-			s.serverErrorResponse(w, r, err)
-			return
-		}
-		if !permissions.Include(permMoviesWrite) {
-			s.notPermittedResponse(w, r)
-			return
-		}
-		next.ServeHTTP(w, r)
+// userPermissions prefers the permissions carried by the request's
+// credentials over the ones stored for the user.
+func (s *app) userPermissions(user *data.User) (data.Permissions, error) {
+	if user.Permissions != nil {
+		return user.Permissions, nil
 	}
-	return s.requireActivatedUser(fn)
+	return s.models.Permissions.GetAllForUser(user.ID)
+}
+
+func (s *app) readPermMovies(next http.Handler) http.Handler {
+	return s.requirePermission(permMoviesRead)(next)
+}
+
+func (s *app) writePermMovies(next http.Handler) http.Handler {
+	return s.requirePermission(permMoviesWrite)(next)
+}
+
+func (s *app) adminPermMovies(next http.Handler) http.Handler {
+	return s.requirePermission(permMoviesAdmin)(next)
+}
+
+func (s *app) reviewPermMovies(next http.Handler) http.Handler {
+	return s.requirePermission(permMoviesReview)(next)
+}
+
+func (s *app) publishPermMovies(next http.Handler) http.Handler {
+	return s.requirePermission(permMoviesPublish)(next)
+}
+
+func (s *app) writePermCinemas(next http.Handler) http.Handler {
+	return s.requirePermission(permCinemasWrite)(next)
+}
+
+func (s *app) writePermBoxOffice(next http.Handler) http.Handler {
+	return s.requirePermission(permBoxOfficeWrite)(next)
+}
+
+func (s *app) writePermAwards(next http.Handler) http.Handler {
+	return s.requirePermission(permAwardsWrite)(next)
+}
+
+func (s *app) adminPermUsers(next http.Handler) http.Handler {
+	return s.requirePermission(permUsersAdmin)(next)
 }
 
 func (s *app) enableCORS(next http.Handler) http.Handler {
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/SemmiDev/chimovies/internal/data"
@@ -23,25 +24,54 @@ func (s *app) createMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	user := s.contextGetUser(r)
+
 	movie := &data.Movie{
-		Title:   input.Title,
-		Year:    input.Year,
-		Runtime: input.Runtime,
-		Genres:  input.Genres,
+		Title:     input.Title,
+		Year:      input.Year,
+		Runtime:   input.Runtime,
+		Genres:    input.Genres,
+		Status:    data.StatusDraft,
+		CreatedBy: user.ID,
 	}
 
 	v := validator.New()
+	qs := r.URL.Query()
+
+	movie.RuntimeFormat = s.readRuntimeFormat(qs, v)
+	force := s.readBool(qs, "force", false, v)
+
 	if data.ValidateMovie(v, movie); !v.Valid() {
 		s.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
+	if !force {
+		includeUnpublished, err := s.canViewUnpublished(user)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+
+		duplicates, err := s.models.Movies.FindDuplicates(movie, user.ID, includeUnpublished)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+		if len(duplicates) > 0 {
+			s.duplicateMovieResponse(w, r, duplicates)
+			return
+		}
+	}
+
 	err = s.models.Movies.Insert(movie)
 	if err != nil {
 		s.serverErrorResponse(w, r, err)
 		return
 	}
 
+	s.warmSimilarMovies(movie)
+
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("api/v1/movies/%d", movie.ID))
 
@@ -58,17 +88,38 @@ func (s *app) showMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	v := validator.New()
+	format := s.readRuntimeFormat(r.URL.Query(), v)
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	movie, err := s.models.Movies.Get(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
-			s.notFoundResponse(w, r)
+			s.redirectMergedMovie(w, r, id)
 		default:
 			s.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
+	visible, err := s.canViewMovie(s.contextGetUser(r), movie)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+	if !visible {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	s.views.view(movie.ID)
+
+	movie.RuntimeFormat = format
+
 	err = s.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
 	if err != nil {
 		s.serverErrorResponse(w, r, err)
@@ -93,6 +144,18 @@ func (s *app) updateMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	user := s.contextGetUser(r)
+
+	allowed, err := s.canModifyMovie(user, movie)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		s.notPermittedResponse(w, r)
+		return
+	}
+
 	if r.Header.Get("X-Expected-Version") != "" {
 		if strconv.FormatInt(int64(movie.Version), 32) != r.Header.Get("X-Expected-Version") {
 			s.editConclictResponse(w, r)
@@ -129,7 +192,10 @@ func (s *app) updateMovieHandler(w http.ResponseWriter, r *http.Request) {
 		movie.Genres = input.Genres
 	}
 
+	movie.UpdatedBy = user.ID
+
 	v := validator.New()
+	movie.RuntimeFormat = s.readRuntimeFormat(r.URL.Query(), v)
 
 	if data.ValidateMovie(v, movie); !v.Valid() {
 		s.failedValidationResponse(w, r, v.Errors)
@@ -147,6 +213,8 @@ func (s *app) updateMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.warmSimilarMovies(movie)
+
 	err = s.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
 	if err != nil {
 		s.serverErrorResponse(w, r, err)
@@ -160,7 +228,28 @@ func (s *app) deleteMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = s.models.Movies.Delete(id)
+	movie, err := s.models.Movies.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	allowed, err := s.canModifyMovie(s.contextGetUser(r), movie)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		s.notPermittedResponse(w, r)
+		return
+	}
+
+	blobKeys, err := s.models.Movies.Delete(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -171,6 +260,8 @@ func (s *app) deleteMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.deleteBlobs(r, blobKeys)
+
 	err = s.writeJSON(w, http.StatusOK, envelope{"message": "movie successfully deleted"}, nil)
 	if err != nil {
 		s.serverErrorResponse(w, r, err)
@@ -179,9 +270,9 @@ func (s *app) deleteMovieHandler(w http.ResponseWriter, r *http.Request) {
 
 func (s *app) listMoviesHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title  string
-		Genres []string
+		data.MovieQuery
 		data.Filters
+		RuntimeFormat data.RuntimeFormat
 	}
 
 	v := validator.New()
@@ -190,24 +281,191 @@ func (s *app) listMoviesHandler(w http.ResponseWriter, r *http.Request) {
 
 	input.Title = s.readString(qs, "title", "")
 	input.Genres = s.readCSV(qs, "genres", []string{})
+	input.CreatedBy = s.readCreatedBy(r, qs, v)
+	input.Status = s.readString(qs, "status", "")
+	input.Tags = data.NormalizeTags(s.readCSV(qs, "tags", []string{}))
+	input.Quote = s.readString(qs, "quote", "")
+	input.WonAward = s.readString(qs, "won_award", "")
 	input.Filters.Page = s.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = s.readInt(qs, "page_size", 20, v)
 	input.Filters.Sort = s.readString(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime"}
+	input.Filters.SortSafelist = []string{"id", "title", "year", "runtime", "gross_usd", "-id", "-title", "-year", "-runtime", "-gross_usd"}
+	input.RuntimeFormat = s.readRuntimeFormat(qs, v)
+
+	if input.Status != "" {
+		v.Check(validator.In(input.Status, data.Statuses...), "status", "must be one of draft, review, published or archived")
+	}
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		s.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	movies, metadata, err := s.models.Movies.GetAll(input.Title, input.Genres, input.Filters)
+	user := s.contextGetUser(r)
+
+	includeUnpublished, err := s.canViewUnpublished(user)
 	if err != nil {
 		s.serverErrorResponse(w, r, err)
 		return
 	}
 
+	input.ViewerID = user.ID
+	input.IncludeUnpublished = includeUnpublished
+
+	movies, metadata, err := s.models.Movies.GetAll(input.MovieQuery, input.Filters)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, movie := range movies {
+		movie.RuntimeFormat = input.RuntimeFormat
+
+		if input.Title != "" {
+			s.views.hit(movie.ID)
+		}
+	}
+
 	err = s.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
 	if err != nil {
 		s.serverErrorResponse(w, r, err)
 	}
 }
+
+func (s *app) mergeMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		CanonicalID int64 `json:"canonical_id"`
+	}
+
+	err = s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.CanonicalID > 0, "canonical_id", "must be provided")
+	v.Check(input.CanonicalID != id, "canonical_id", "must be different from the merged movie")
+
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	blobKeys, err := s.models.Movies.Merge(id, input.CanonicalID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	s.deleteBlobs(r, blobKeys)
+
+	movie, err := s.models.Movies.Get(input.CanonicalID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) redirectMergedMovie(w http.ResponseWriter, r *http.Request, id int64) {
+	movieID, err := s.models.Movies.GetRedirect(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	location := url.URL{Path: fmt.Sprintf("/api/v1/movies/%d", movieID), RawQuery: r.URL.RawQuery}
+
+	headers := make(http.Header)
+	headers.Set("Location", location.String())
+
+	err = s.writeJSON(w, http.StatusMovedPermanently, envelope{"movie_id": movieID}, headers)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// readVisibleMovie looks up the movie addressed by the {id} URL parameter and
+// checks that the current user may see it, writing the error response itself
+// when either fails.
+func (s *app) readVisibleMovie(w http.ResponseWriter, r *http.Request) (*data.Movie, bool) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	movie, err := s.models.Movies.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	visible, err := s.canViewMovie(s.contextGetUser(r), movie)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+	if !visible {
+		s.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	return movie, true
+}
+
+func (s *app) canModifyMovie(user *data.User, movie *data.Movie) (bool, error) {
+	if movie.CreatedBy != 0 && movie.CreatedBy == user.ID {
+		return true, nil
+	}
+
+	permissions, err := s.userPermissions(user)
+	if err != nil {
+		return false, err
+	}
+	return permissions.Include(permMoviesAdmin), nil
+}
+
+func (s *app) readCreatedBy(r *http.Request, qs url.Values, v *validator.Validator) int64 {
+	createdBy := qs.Get("created_by")
+
+	switch createdBy {
+	case "":
+		return 0
+	case "me":
+		return s.contextGetUser(r).ID
+	}
+
+	id, err := strconv.ParseInt(createdBy, 10, 64)
+	if err != nil || id < 1 {
+		v.AddError("created_by", "must be me or a user id")
+		return 0
+	}
+	return id
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+)
+
+// oauthErrorResponse writes an error response in the format of RFC 6749,
+// section 5.2, rather than the API's usual error envelope.
+func (s *app) oauthErrorResponse(w http.ResponseWriter, r *http.Request, status int, code, description string) {
+	headers := make(http.Header)
+	headers.Set("Cache-Control", "no-store")
+	headers.Set("Pragma", "no-cache")
+
+	if status == http.StatusUnauthorized {
+		headers.Set("WWW-Authenticate", `Basic realm="chimovies"`)
+	}
+
+	err := s.writeJSON(w, status, envelope{"error": code, "error_description": description}, headers)
+	if err != nil {
+		s.logError(r, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// readClientCredentials reads the client id and secret from HTTP Basic
+// authentication or, failing that, from the form body. Per RFC 6749, section
+// 2.3.1, the Basic credentials are form-urlencoded before being joined.
+func readClientCredentials(r *http.Request) (string, string, error) {
+	id, secret, basic := r.BasicAuth()
+	if basic {
+		if r.PostForm.Get("client_id") != "" || r.PostForm.Get("client_secret") != "" {
+			return "", "", errors.New("client credentials must be sent in only one way")
+		}
+
+		var err error
+		if id, err = url.QueryUnescape(id); err != nil {
+			return "", "", errors.New("malformed client id")
+		}
+		if secret, err = url.QueryUnescape(secret); err != nil {
+			return "", "", errors.New("malformed client secret")
+		}
+		return id, secret, nil
+	}
+
+	return r.PostForm.Get("client_id"), r.PostForm.Get("client_secret"), nil
+}
+
+// oauthTokenHandler implements the client credentials grant of RFC 6749,
+// section 4.4. Clients get an authentication token for their service account,
+// limited to the client's permissions, or to the requested scope.
+func (s *app) oauthTokenHandler(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" {
+		s.oauthErrorResponse(w, r, http.StatusBadRequest, "invalid_request", "the request body must be application/x-www-form-urlencoded")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1_048_576)
+
+	err := r.ParseForm()
+	if err != nil {
+		s.oauthErrorResponse(w, r, http.StatusBadRequest, "invalid_request", "the request body could not be parsed")
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+	case "":
+		s.oauthErrorResponse(w, r, http.StatusBadRequest, "invalid_request", "grant_type must be provided")
+		return
+	default:
+		s.oauthErrorResponse(w, r, http.StatusBadRequest, "unsupported_grant_type", "only the client_credentials grant is supported")
+		return
+	}
+
+	clientID, clientSecret, err := readClientCredentials(r)
+	if err != nil {
+		s.oauthErrorResponse(w, r, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	if clientID == "" || clientSecret == "" {
+		s.oauthErrorResponse(w, r, http.StatusUnauthorized, "invalid_client", "client authentication is required")
+		return
+	}
+
+	client, err := s.models.OAuthClients.Get(clientID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if client == nil || !client.SecretMatches(clientSecret) {
+		s.oauthErrorResponse(w, r, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	user, err := s.models.Users.Get(client.UserID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !user.Activated {
+		s.oauthErrorResponse(w, r, http.StatusBadRequest, "unauthorized_client", "the client's service account is not activated")
+		return
+	}
+
+	granted, err := s.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The client may never do more than its service account currently can.
+	var allowed data.Permissions
+	for _, code := range client.Permissions {
+		if granted.Include(code) {
+			allowed = append(allowed, code)
+		}
+	}
+
+	permissions := allowed
+	if scope := r.PostForm.Get("scope"); scope != "" {
+		permissions = nil
+		for _, code := range strings.Fields(scope) {
+			if !allowed.Include(code) {
+				s.oauthErrorResponse(w, r, http.StatusBadRequest, "invalid_scope", fmt.Sprintf("the client is not allowed the %s scope", code))
+				return
+			}
+			if !permissions.Include(code) {
+				permissions = append(permissions, code)
+			}
+		}
+	}
+
+	if len(permissions) == 0 {
+		s.oauthErrorResponse(w, r, http.StatusBadRequest, "invalid_scope", "the client has no permissions that can be granted")
+		return
+	}
+
+	var token *data.Token
+
+	if s.config.TokenFormat == tokenFormatJWT {
+		user.Permissions = permissions
+		user.ClientID = client.ID
+		token, err = s.signAccessToken(user, nil)
+	} else {
+		token, err = s.models.Tokens.NewForClient(client, s.config.AccessTokenTTL, permissions)
+	}
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Cache-Control", "no-store")
+	headers.Set("Pragma", "no-cache")
+
+	env := envelope{
+		"access_token": token.Plaintext,
+		"token_type":   "Bearer",
+		"expires_in":   int(s.config.AccessTokenTTL.Seconds()),
+		"scope":        strings.Join(permissions, " "),
+	}
+
+	err = s.writeJSON(w, http.StatusOK, env, headers)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// createOAuthClient registers a client for an existing service account user
+// and prints its credentials. The secret cannot be shown again.
+func (s *app) createOAuthClient(args []string) error {
+	fs := flag.NewFlagSet("create-oauth-client", flag.ContinueOnError)
+	name := fs.String("name", "", "name of the client")
+	email := fs.String("user", "", "email address of the service account user")
+	permissions := fs.String("permissions", "", "comma separated permission codes")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *name == "" || *email == "" || *permissions == "" {
+		return errors.New("name, user and permissions must be provided")
+	}
+
+	user, err := s.models.Users.GetByEmail(*email)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return fmt.Errorf("no user with the email address %s", *email)
+		}
+		return err
+	}
+
+	granted, err := s.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		return err
+	}
+
+	client := &data.OAuthClient{Name: *name, UserID: user.ID}
+
+	for _, code := range strings.Split(*permissions, ",") {
+		code = strings.TrimSpace(code)
+		if !granted.Include(code) {
+			return fmt.Errorf("%s does not have the %s permission", *email, code)
+		}
+		if !client.Permissions.Include(code) {
+			client.Permissions = append(client.Permissions, code)
+		}
+	}
+
+	err = s.models.OAuthClients.Insert(client)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	return enc.Encode(client)
+}
+
+func (s *app) deleteOAuthClient(args []string) error {
+	fs := flag.NewFlagSet("delete-oauth-client", flag.ContinueOnError)
+	id := fs.String("id", "", "client id")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	err = s.models.OAuthClients.Delete(*id)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return fmt.Errorf("no client with the id %s", *id)
+		}
+		return err
+	}
+
+	s.logger.PrintInfo("oauth client deleted", map[string]string{"client_id": *id})
+	return nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+func (s *app) holdSeatsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Seats []string `json:"seats"`
+	}
+
+	err = s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	includeUnpublished, err := s.canViewUnpublished(user)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	showtime, err := s.models.Showtimes.Get(id, user.ID, includeUnpublished)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	screen, err := s.models.Screens.Get(showtime.ScreenID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	reservation := &data.Reservation{
+		ShowtimeID: showtime.ID,
+		UserID:     user.ID,
+		Seats:      input.Seats,
+	}
+
+	v := validator.New()
+	v.Check(showtime.StartsAt.After(time.Now()), "showtime", "has already started")
+
+	if data.ValidateReservation(v, reservation, screen.SeatMap); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.Reservations.Hold(reservation, s.config.HoldTTL)
+	if err != nil {
+		var seatsTaken *data.SeatsTakenError
+		switch {
+		case errors.As(err, &seatsTaken):
+			s.seatsTakenResponse(w, r, seatsTaken.Seats)
+		case errors.Is(err, data.ErrSeatLimit):
+			v.AddError("seats", fmt.Sprintf("must not bring your seats for this showtime to more than %d", data.MaxSeatsPerUser))
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/reservations/%d", reservation.ID))
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"reservation": reservation}, headers)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// readReservation looks up the current user's reservation addressed by the
+// {id} URL parameter, writing the error response itself when it cannot be
+// found. Other users' reservations are reported as not found.
+func (s *app) readReservation(w http.ResponseWriter, r *http.Request) (*data.Reservation, bool) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	reservation, err := s.models.Reservations.Get(id, s.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return reservation, true
+}
+
+func (s *app) showReservationHandler(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := s.readReservation(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.writeJSON(w, http.StatusOK, envelope{"reservation": reservation}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) confirmReservationHandler(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := s.readReservation(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.models.Reservations.Confirm(reservation)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrHoldExpired):
+			s.holdExpiredResponse(w, r)
+		case errors.Is(err, data.ErrInvalidReservationState):
+			v := validator.New()
+			v.AddError("status", fmt.Sprintf("a %s reservation cannot be confirmed", reservation.Status))
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"reservation": reservation}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) cancelReservationHandler(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := s.readReservation(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.models.Reservations.Cancel(reservation)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrShowtimeStarted):
+			v := validator.New()
+			v.AddError("showtime", "has already started")
+			s.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrInvalidReservationState):
+			v := validator.New()
+			v.AddError("status", fmt.Sprintf("a %s reservation cannot be cancelled", reservation.Status))
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"reservation": reservation}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) releaseExpiredHolds() error {
+	released, err := s.models.Reservations.ReleaseExpired()
+	if err != nil {
+		return err
+	}
+
+	if released > 0 {
+		s.logger.PrintInfo("released expired seat holds", map[string]string{
+			"seats": strconv.FormatInt(released, 10),
+		})
+	}
+
+	return nil
+}
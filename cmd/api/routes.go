@@ -3,6 +3,7 @@ package main
 import (
 	"expvar"
 
+	"github.com/SemmiDev/chimovies/internal/data"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
 )
@@ -25,13 +26,106 @@ func (s *app) routes() *chi.Mux {
 	router.Get("/api/v1/healthcheck", s.healthcheckHandler)
 	router.With(s.readPermMovies).Get("/api/v1/movies", s.listMoviesHandler)
 	router.With(s.writePermMovies).Post("/api/v1/movies", s.createMovieHandler)
+	router.With(s.readPermMovies).Get("/api/v1/movies/trending", s.listTrendingMoviesHandler)
 	router.With(s.readPermMovies).Get("/api/v1/movies/{id}", s.showMovieHandler)
+	router.With(s.readPermMovies).Get("/api/v1/movies/{id}/similar", s.listSimilarMoviesHandler)
 	router.With(s.writePermMovies).Patch("/api/v1/movies/{id}", s.updateMovieHandler)
 	router.With(s.writePermMovies).Delete("/api/v1/movies/{id}", s.deleteMovieHandler)
+	router.With(s.adminPermMovies).Post("/api/v1/movies/{id}/merge", s.mergeMovieHandler)
+	router.With(s.writePermMovies).Post("/api/v1/movies/{id}/submit", s.transitionMovieHandler(data.StatusDraft, data.StatusReview))
+	router.With(s.reviewPermMovies).Post("/api/v1/movies/{id}/reject", s.transitionMovieHandler(data.StatusReview, data.StatusDraft))
+	router.With(s.publishPermMovies).Post("/api/v1/movies/{id}/publish", s.transitionMovieHandler(data.StatusReview, data.StatusPublished))
+	router.With(s.publishPermMovies).Post("/api/v1/movies/{id}/archive", s.transitionMovieHandler(data.StatusPublished, data.StatusArchived))
+	router.With(s.publishPermMovies).Post("/api/v1/movies/{id}/restore", s.transitionMovieHandler(data.StatusArchived, data.StatusDraft))
+	router.With(s.readPermMovies).Get("/api/v1/movies/{id}/subtitles", s.listSubtitlesHandler)
+	router.With(s.readPermMovies).Get("/api/v1/movies/{id}/subtitles/{language}", s.showSubtitleHandler)
+	router.With(s.writePermMovies).Put("/api/v1/movies/{id}/subtitles/{language}", s.uploadSubtitleHandler)
+	router.With(s.writePermMovies).Delete("/api/v1/movies/{id}/subtitles/{language}", s.deleteSubtitleHandler)
+
+	router.With(s.readPermMovies).Get("/api/v1/movies/{id}/showtimes", s.listMovieShowtimesHandler)
+	router.With(s.readPermMovies).Get("/api/v1/movies/{id}/box-office", s.showBoxOfficeHandler)
+	router.With(s.writePermBoxOffice).Put("/api/v1/movies/{id}/box-office/budget", s.updateBudgetHandler)
+	router.With(s.writePermBoxOffice).Delete("/api/v1/movies/{id}/box-office/budget", s.deleteBudgetHandler)
+	router.With(s.writePermBoxOffice).Put("/api/v1/movies/{id}/box-office/regions/{region}", s.updateGrossHandler)
+	router.With(s.writePermBoxOffice).Delete("/api/v1/movies/{id}/box-office/regions/{region}", s.deleteGrossHandler)
+
+	router.With(s.readPermMovies).Get("/api/v1/movies/{id}/awards", s.listMovieAwardsHandler)
+	router.With(s.readPermMovies).Get("/api/v1/movies/{id}/tags", s.listMovieTagsHandler)
+	router.With(s.readPermMovies).Post("/api/v1/movies/{id}/tags", s.requireActivatedUser(s.tagMovieHandler))
+	router.With(s.readPermMovies).Delete("/api/v1/movies/{id}/tags/{tag}", s.requireActivatedUser(s.untagMovieHandler))
+	router.With(s.readPermMovies).Get("/api/v1/movies/{id}/videos", s.listVideosHandler)
+	router.With(s.writePermMovies).Post("/api/v1/movies/{id}/videos", s.createVideoHandler)
+	router.With(s.writePermMovies).Post("/api/v1/movies/{id}/videos/check", s.checkVideosHandler)
+	router.With(s.writePermMovies).Delete("/api/v1/videos/{id}", s.deleteVideoHandler)
+
+	router.With(s.readPermMovies).Get("/api/v1/tags/popular", s.listPopularTagsHandler)
+
+	router.With(s.readPermMovies).Get("/api/v1/awards", s.listAwardEventsHandler)
+	router.With(s.writePermAwards).Post("/api/v1/awards", s.createAwardEventHandler)
+	router.With(s.readPermMovies).Get("/api/v1/awards/{id}", s.showAwardEventHandler)
+	router.With(s.writePermAwards).Delete("/api/v1/awards/{id}", s.deleteAwardEventHandler)
+	router.With(s.writePermAwards).Post("/api/v1/awards/{id}/nominations/import", s.importNominationsHandler)
+
+	router.With(s.readPermMovies).Get("/api/v1/box-office/leaderboard", s.boxOfficeLeaderboardHandler)
+	router.With(s.readPermMovies).Get("/api/v1/exchange-rates", s.listExchangeRatesHandler)
+	router.With(s.writePermBoxOffice).Put("/api/v1/exchange-rates/{currency}", s.updateExchangeRateHandler)
+
+	router.With(s.readPermMovies).Get("/api/v1/cinemas", s.listCinemasHandler)
+	router.With(s.writePermCinemas).Post("/api/v1/cinemas", s.createCinemaHandler)
+	router.With(s.readPermMovies).Get("/api/v1/cinemas/{id}", s.showCinemaHandler)
+	router.With(s.writePermCinemas).Patch("/api/v1/cinemas/{id}", s.updateCinemaHandler)
+	router.With(s.writePermCinemas).Delete("/api/v1/cinemas/{id}", s.deleteCinemaHandler)
+	router.With(s.writePermCinemas).Post("/api/v1/cinemas/{id}/screens", s.createScreenHandler)
+	router.With(s.readPermMovies).Get("/api/v1/cinemas/{id}/showtimes", s.listCinemaShowtimesHandler)
+	router.With(s.readPermMovies).Get("/api/v1/screens/{id}", s.showScreenHandler)
+	router.With(s.writePermCinemas).Delete("/api/v1/screens/{id}", s.deleteScreenHandler)
+	router.With(s.writePermCinemas).Post("/api/v1/showtimes", s.createShowtimeHandler)
+	router.With(s.readPermMovies).Get("/api/v1/showtimes/{id}", s.showShowtimeHandler)
+	router.With(s.writePermCinemas).Delete("/api/v1/showtimes/{id}", s.deleteShowtimeHandler)
+	router.Post("/api/v1/showtimes/{id}/reservations", s.requireActivatedUser(s.holdSeatsHandler))
+	router.Get("/api/v1/reservations/{id}", s.requireActivatedUser(s.showReservationHandler))
+	router.Post("/api/v1/reservations/{id}/confirm", s.requireActivatedUser(s.confirmReservationHandler))
+	router.Post("/api/v1/reservations/{id}/cancel", s.requireActivatedUser(s.cancelReservationHandler))
+
+	router.With(s.readPermMovies).Get("/api/v1/series", s.listSeriesHandler)
+	router.With(s.writePermMovies).Post("/api/v1/series", s.createSeriesHandler)
+	router.With(s.readPermMovies).Get("/api/v1/series/{id}", s.showSeriesHandler)
+	router.With(s.writePermMovies).Patch("/api/v1/series/{id}", s.updateSeriesHandler)
+	router.With(s.writePermMovies).Delete("/api/v1/series/{id}", s.deleteSeriesHandler)
+	router.With(s.readPermMovies).Get("/api/v1/series/{id}/seasons", s.listSeasonsHandler)
+	router.With(s.writePermMovies).Post("/api/v1/series/{id}/seasons", s.createSeasonHandler)
+	router.With(s.readPermMovies).Get("/api/v1/series/{id}/seasons/{season}", s.showSeasonHandler)
+	router.With(s.writePermMovies).Delete("/api/v1/series/{id}/seasons/{season}", s.deleteSeasonHandler)
+	router.With(s.readPermMovies).Get("/api/v1/series/{id}/seasons/{season}/episodes", s.listEpisodesHandler)
+	router.With(s.writePermMovies).Post("/api/v1/series/{id}/seasons/{season}/episodes", s.createEpisodeHandler)
+	router.With(s.readPermMovies).Get("/api/v1/series/{id}/seasons/{season}/episodes/{episode}", s.showEpisodeHandler)
+	router.With(s.writePermMovies).Patch("/api/v1/series/{id}/seasons/{season}/episodes/{episode}", s.updateEpisodeHandler)
+	router.With(s.writePermMovies).Delete("/api/v1/series/{id}/seasons/{season}/episodes/{episode}", s.deleteEpisodeHandler)
+
+	router.With(s.readPermMovies).Get("/api/v1/search", s.searchHandler)
 
 	router.Post("/api/v1/users", s.registerUserHandler)
 	router.Put("/api/v1/users/activated", s.activateUserHandler)
+	router.Put("/api/v1/users/password", s.updateUserPasswordHandler)
+	router.Get("/api/v1/users/me/sessions", s.requireAuthenticatedUser(s.requireAccountOwner(s.listSessionsHandler)))
+	router.Delete("/api/v1/users/me/sessions/{id}", s.requireAuthenticatedUser(s.requireAccountOwner(s.deleteSessionHandler)))
+	router.Post("/api/v1/users/me/mfa/totp", s.requireActivatedUser(s.requireAccountOwner(s.enrollTOTPHandler)))
+	router.Post("/api/v1/users/me/mfa/totp/confirm", s.requireActivatedUser(s.requireAccountOwner(s.confirmTOTPHandler)))
+	router.Delete("/api/v1/users/me/mfa/totp", s.requireActivatedUser(s.requireAccountOwner(s.disableTOTPHandler)))
+	router.With(s.adminPermUsers).Delete("/api/v1/users/{id}/mfa", s.resetUserMFAHandler)
+	router.Get("/api/v1/users/me/api-keys", s.requireActivatedUser(s.requireAccountOwner(s.listAPIKeysHandler)))
+	router.Post("/api/v1/users/me/api-keys", s.requireActivatedUser(s.requireAccountOwner(s.createAPIKeyHandler)))
+	router.Delete("/api/v1/users/me/api-keys/{id}", s.requireActivatedUser(s.requireAccountOwner(s.deleteAPIKeyHandler)))
+	router.Post("/api/v1/tokens/activation", s.createActivationTokenHandler)
 	router.Post("/api/v1/tokens/authentication", s.createAuthenticationTokenHandler)
+	router.Post("/api/v1/tokens/authentication/mfa", s.createMFAAuthenticationTokenHandler)
+	router.Delete("/api/v1/tokens/authentication", s.requireAuthenticatedUser(s.deleteAuthenticationTokenHandler))
+	router.Delete("/api/v1/tokens/authentication/all", s.requireAuthenticatedUser(s.requireAccountOwner(s.deleteAllAuthenticationTokensHandler)))
+	router.Post("/api/v1/tokens/refresh", s.refreshAuthenticationTokenHandler)
+	router.Post("/api/v1/tokens/password-reset", s.createPasswordResetTokenHandler)
+
+	router.Post("/oauth/token", s.oauthTokenHandler)
+	router.Get("/.well-known/jwks.json", s.jwksHandler)
 
 	router.Get("/api/debug/vars", expvar.Handler().ServeHTTP)
 	return router
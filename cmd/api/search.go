@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+func (s *app) searchHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Query string
+		Type  string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Query = s.readString(qs, "q", "")
+	input.Type = s.readString(qs, "type", "")
+	input.Filters.Page = s.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = s.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = s.readString(qs, "sort", "-rank")
+	input.Filters.SortSafelist = []string{"-rank"}
+
+	v.Check(input.Query != "", "q", "must be provided")
+	if input.Type != "" {
+		v.Check(validator.In(input.Type, data.SearchTypeMovie, data.SearchTypeSeries), "type", "must be movie or series")
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	results, metadata, err := s.models.Search.Search(input.Query, input.Type, input.Filters)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, result := range results {
+		if result.Type == data.SearchTypeMovie {
+			s.views.hit(result.ID)
+		}
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"results": results, "metadata": metadata}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+func (s *app) createSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title     string   `json:"title"`
+		StartYear int32    `json:"start_year"`
+		EndYear   int32    `json:"end_year"`
+		Genres    []string `json:"genres"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	series := &data.Series{
+		Title:     input.Title,
+		StartYear: input.StartYear,
+		EndYear:   input.EndYear,
+		Genres:    input.Genres,
+	}
+
+	v := validator.New()
+	if data.ValidateSeries(v, series); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.Series.Insert(series)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/series/%d", series.ID))
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"series": series}, headers)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) showSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	series, err := s.models.Series.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	series.Seasons, err = s.models.Seasons.GetAllForSeries(series.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"series": series}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) updateSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	series, err := s.models.Series.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Title     *string  `json:"title"`
+		StartYear *int32   `json:"start_year"`
+		EndYear   *int32   `json:"end_year"`
+		Genres    []string `json:"genres"`
+	}
+
+	err = s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Title != nil {
+		series.Title = *input.Title
+	}
+
+	if input.StartYear != nil {
+		series.StartYear = *input.StartYear
+	}
+
+	if input.EndYear != nil {
+		series.EndYear = *input.EndYear
+	}
+
+	if input.Genres != nil {
+		series.Genres = input.Genres
+	}
+
+	v := validator.New()
+
+	if data.ValidateSeries(v, series); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.Series.Update(series)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			s.editConclictResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"series": series}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) deleteSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	err = s.models.Series.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "series successfully deleted"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) listSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title  string
+		Genres []string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Title = s.readString(qs, "title", "")
+	input.Genres = s.readCSV(qs, "genres", []string{})
+	input.Filters.Page = s.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = s.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = s.readString(qs, "sort", "id")
+	input.Filters.SortSafelist = []string{"id", "title", "start_year", "-id", "-title", "-start_year"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	series, metadata, err := s.models.Series.GetAll(input.Title, input.Genres, input.Filters)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"series": series, "metadata": metadata}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// readSeason looks up the season addressed by the {id} and {season} URL
+// parameters, writing the error response itself when it cannot be found.
+func (s *app) readSeason(w http.ResponseWriter, r *http.Request) (*data.Season, bool) {
+	seriesID, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	number, err := s.readNumberParam(r, "season")
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	season, err := s.models.Seasons.Get(seriesID, number)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return season, true
+}
+
+func (s *app) createSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	seriesID, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Number int32  `json:"number"`
+		Title  string `json:"title"`
+	}
+
+	err = s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	_, err = s.models.Series.Get(seriesID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	season := &data.Season{
+		SeriesID: seriesID,
+		Number:   input.Number,
+		Title:    input.Title,
+	}
+
+	v := validator.New()
+	if data.ValidateSeason(v, season); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.Seasons.Insert(season)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateNumber):
+			v.AddError("number", "a season with this number already exists")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/series/%d/seasons/%d", seriesID, season.Number))
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"season": season}, headers)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) listSeasonsHandler(w http.ResponseWriter, r *http.Request) {
+	seriesID, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = s.models.Series.Get(seriesID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	seasons, err := s.models.Seasons.GetAllForSeries(seriesID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"seasons": seasons}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) showSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	format := s.readRuntimeFormat(r.URL.Query(), v)
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	season, ok := s.readSeason(w, r)
+	if !ok {
+		return
+	}
+
+	episodes, err := s.models.Episodes.GetAllForSeason(season.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, episode := range episodes {
+		episode.RuntimeFormat = format
+	}
+	season.Episodes = episodes
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"season": season}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) deleteSeasonHandler(w http.ResponseWriter, r *http.Request) {
+	season, ok := s.readSeason(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.models.Seasons.Delete(season.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "season successfully deleted"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -20,6 +21,8 @@ func (s *app) serve() error {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	s.startWorkers()
+
 	shutdownError := make(chan error)
 
 	go func() {
@@ -34,7 +37,36 @@ func (s *app) serve() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		shutdownError <- srv.Shutdown(ctx)
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			shutdownError <- err
+			return
+		}
+
+		// Every buffer is flushed even if one fails, so that as little as
+		// possible is lost.
+		var failed []string
+		flushes := []struct {
+			name  string
+			flush func() error
+		}{
+			{"views", s.flushViews},
+			{"sessions", s.flushSessions},
+			{"api keys", s.flushAPIKeys},
+		}
+
+		for _, f := range flushes {
+			if err := f.flush(); err != nil {
+				failed = append(failed, fmt.Sprintf("flush %s: %v", f.name, err))
+			}
+		}
+
+		if len(failed) > 0 {
+			shutdownError <- errors.New(strings.Join(failed, "; "))
+			return
+		}
+
+		shutdownError <- nil
 	}()
 
 	s.logger.PrintInfo("starting server", map[string]string{
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+)
+
+// sessionTracker buffers the last time each authentication token was used,
+// so that authenticated requests don't each write to the tokens table.
+type sessionTracker struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+func newSessionTracker() *sessionTracker {
+	return &sessionTracker{used: make(map[string]time.Time)}
+}
+
+func (t *sessionTracker) use(hash []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.used[string(hash)] = time.Now()
+}
+
+func (t *sessionTracker) drain() map[string]time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	used := t.used
+	t.used = make(map[string]time.Time)
+	return used
+}
+
+func (t *sessionTracker) restore(used map[string]time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for hash, usedAt := range used {
+		if usedAt.After(t.used[hash]) {
+			t.used[hash] = usedAt
+		}
+	}
+}
+
+func (s *app) flushSessions() error {
+	used := s.sessions.drain()
+
+	err := s.models.Tokens.Touch(used)
+	if err != nil {
+		s.sessions.restore(used)
+		return err
+	}
+	return nil
+}
+
+func (s *app) listSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := s.contextGetUser(r)
+
+	sessions, err := s.models.Tokens.GetAllSessions(user.ID, s.contextGetToken(r), s.contextGetSession(r))
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"sessions": sessions}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) deleteSessionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	err = s.models.Tokens.DeleteSession(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "session successfully revoked"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
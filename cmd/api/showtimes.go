@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+func (s *app) createShowtimeHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MovieID  int64     `json:"movie_id"`
+		ScreenID int64     `json:"screen_id"`
+		StartsAt time.Time `json:"starts_at"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	movie, err := s.models.Movies.Get(input.MovieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("movie_id", "must refer to an existing movie")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	showtime := &data.Showtime{
+		MovieID:  movie.ID,
+		ScreenID: input.ScreenID,
+		StartsAt: input.StartsAt,
+		EndsAt:   input.StartsAt.Add(time.Duration(movie.Runtime) * time.Minute),
+	}
+
+	if data.ValidateShowtime(v, showtime, movie.Runtime); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.Showtimes.Insert(showtime, s.config.ShowtimeCleaningBuffer)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("screen_id", "must refer to an existing screen")
+			s.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrShowtimeOverlap):
+			v.AddError("starts_at", "overlaps another showtime on this screen")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/showtimes/%d", showtime.ID))
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"showtime": showtime}, headers)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) showShowtimeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	includeUnpublished, err := s.canViewUnpublished(user)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	showtime, err := s.models.Showtimes.Get(id, user.ID, includeUnpublished)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"showtime": showtime}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) deleteShowtimeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	err = s.models.Showtimes.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "showtime successfully deleted"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) listMovieShowtimesHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	s.listShowtimes(w, r, data.ShowtimeQuery{MovieID: movie.ID})
+}
+
+func (s *app) listCinemaShowtimesHandler(w http.ResponseWriter, r *http.Request) {
+	cinema, ok := s.readCinema(w, r)
+	if !ok {
+		return
+	}
+
+	s.listShowtimes(w, r, data.ShowtimeQuery{CinemaID: cinema.ID})
+}
+
+// listShowtimes serves the showtimes matching q within ?days= days from
+// ?date=, which defaults to today in each cinema's time zone.
+func (s *app) listShowtimes(w http.ResponseWriter, r *http.Request, q data.ShowtimeQuery) {
+	var filters data.Filters
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	q.City = s.readString(qs, "city", "")
+	q.Date = s.readDate(qs, "date", time.Time{}, v)
+	q.Days = s.readInt(qs, "days", 1, v)
+	filters.Page = s.readInt(qs, "page", 1, v)
+	filters.PageSize = s.readInt(qs, "page_size", 20, v)
+	filters.Sort = s.readString(qs, "sort", "starts_at")
+	filters.SortSafelist = []string{"starts_at", "-starts_at"}
+
+	v.Check(q.Days >= 1, "days", "must be greater than zero")
+	v.Check(q.Days <= 14, "days", "must be a maximum of 14")
+
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	includeUnpublished, err := s.canViewUnpublished(user)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	q.ViewerID = user.ID
+	q.IncludeUnpublished = includeUnpublished
+
+	showtimes, metadata, err := s.models.Showtimes.GetAll(q, filters)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"showtimes": showtimes, "metadata": metadata}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
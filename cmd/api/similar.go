@@ -0,0 +1,202 @@
+package main
+
+import (
+	"container/list"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+type similarCacheEntry struct {
+	movieID int64
+	version int32
+	ranking data.SimilarRanking
+	expires time.Time
+}
+
+// similarCache keeps the rankings of the most recently requested movies, up
+// to size of them, evicting the least recently used one when full.
+type similarCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	order   *list.List
+	entries map[int64]*list.Element
+}
+
+func newSimilarCache(ttl time.Duration, size int) *similarCache {
+	c := &similarCache{
+		ttl:     ttl,
+		size:    size,
+		order:   list.New(),
+		entries: make(map[int64]*list.Element),
+	}
+
+	go func() {
+		for {
+			time.Sleep(time.Minute)
+			c.mu.Lock()
+
+			for _, element := range c.entries {
+				if time.Now().After(element.Value.(*similarCacheEntry).expires) {
+					c.remove(element)
+				}
+			}
+			c.mu.Unlock()
+		}
+	}()
+
+	return c
+}
+
+func (c *similarCache) get(movie *data.Movie) (data.SimilarRanking, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, found := c.entries[movie.ID]
+	if !found {
+		return data.SimilarRanking{}, false
+	}
+
+	entry := element.Value.(*similarCacheEntry)
+	if entry.version != movie.Version || time.Now().After(entry.expires) {
+		c.remove(element)
+		return data.SimilarRanking{}, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.ranking, true
+}
+
+func (c *similarCache) set(movie *data.Movie, ranking data.SimilarRanking) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, found := c.entries[movie.ID]; found {
+		c.remove(element)
+	}
+
+	c.entries[movie.ID] = c.order.PushFront(&similarCacheEntry{
+		movieID: movie.ID,
+		version: movie.Version,
+		ranking: ranking,
+		expires: time.Now().Add(c.ttl),
+	})
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove must be called with mu held.
+func (c *similarCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*similarCacheEntry).movieID)
+}
+
+func (s *app) rankSimilarMovies(movie *data.Movie) (data.SimilarRanking, error) {
+	if ranking, found := s.similar.get(movie); found {
+		return ranking, nil
+	}
+
+	candidates, err := s.models.Movies.GetSimilarCandidates(movie)
+	if err != nil {
+		return data.SimilarRanking{}, err
+	}
+
+	weights := data.SimilarityWeights{
+		Genres:  s.config.SimilarWeightGenres,
+		Year:    s.config.SimilarWeightYear,
+		Runtime: s.config.SimilarWeightRuntime,
+	}
+
+	ranking := data.NewSimilarRanking(data.RankSimilar(movie, candidates, weights))
+	s.similar.set(movie, ranking)
+
+	return ranking, nil
+}
+
+func (s *app) listSimilarMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.Filters
+		RuntimeFormat data.RuntimeFormat
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Filters.Page = s.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = s.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = s.readString(qs, "sort", "-score")
+	input.Filters.SortSafelist = []string{"-score"}
+	input.RuntimeFormat = s.readRuntimeFormat(qs, v)
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movie, err := s.models.Movies.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	visible, err := s.canViewMovie(s.contextGetUser(r), movie)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+	if !visible {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	ranking, err := s.rankSimilarMovies(movie)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Only the ranking is cached; the movies are looked up again so that ones
+	// unpublished or deleted since it was computed are left out.
+	movies, metadata, err := s.models.Movies.GetRanked(ranking, input.Filters)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, similar := range movies {
+		similar.Movie.RuntimeFormat = input.RuntimeFormat
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) warmSimilarMovies(movie *data.Movie) {
+	s.background(func() {
+		_, err := s.rankSimilarMovies(movie)
+		if err != nil {
+			s.logger.PrintError(err, nil)
+		}
+	})
+}
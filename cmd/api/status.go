@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+func (s *app) canViewUnpublished(user *data.User) (bool, error) {
+	permissions, err := s.userPermissions(user)
+	if err != nil {
+		return false, err
+	}
+	return permissions.Include(permMoviesReview) || permissions.Include(permMoviesPublish), nil
+}
+
+func (s *app) canViewMovie(user *data.User, movie *data.Movie) (bool, error) {
+	if movie.Status == data.StatusPublished {
+		return true, nil
+	}
+	if movie.CreatedBy != 0 && movie.CreatedBy == user.ID {
+		return true, nil
+	}
+	return s.canViewUnpublished(user)
+}
+
+func (s *app) transitionMovieHandler(from, to string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := s.readIDParam(r)
+		if err != nil {
+			s.notFoundResponse(w, r)
+			return
+		}
+
+		var input struct {
+			PublishAt *time.Time `json:"publish_at"`
+		}
+
+		if to == data.StatusPublished && r.ContentLength != 0 {
+			err = s.readJSON(w, r, &input)
+			if err != nil {
+				s.badRequestResponse(w, r, err)
+				return
+			}
+		}
+
+		movie, err := s.models.Movies.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				s.notFoundResponse(w, r)
+			default:
+				s.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		user := s.contextGetUser(r)
+
+		if to == data.StatusReview {
+			allowed, err := s.canModifyMovie(user, movie)
+			if err != nil {
+				s.serverErrorResponse(w, r, err)
+				return
+			}
+			if !allowed {
+				s.notPermittedResponse(w, r)
+				return
+			}
+		}
+
+		movie.UpdatedBy = user.ID
+
+		err = data.ErrInvalidTransition
+		if movie.Status == from {
+			err = s.models.Movies.Transition(movie, to, input.PublishAt)
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrInvalidTransition):
+				v := validator.New()
+				v.AddError("status", "cannot change from "+movie.Status+" to "+to)
+				s.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, data.ErrEditConflict):
+				s.editConclictResponse(w, r)
+			default:
+				s.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		err = s.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+		}
+	}
+}
+
+func (s *app) publishScheduledMovies() error {
+	published, err := s.models.Movies.PublishScheduled()
+	if err != nil {
+		return err
+	}
+
+	if published > 0 {
+		s.logger.PrintInfo("published scheduled movies", map[string]string{
+			"count": strconv.FormatInt(published, 10),
+		})
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SemmiDev/chimovies/internal/blob"
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/subtitles"
+	"github.com/SemmiDev/chimovies/internal/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+const maxSubtitleBytes = 5 * 1_048_576
+
+func (s *app) uploadSubtitleHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	language := chi.URLParam(r, "language")
+
+	v := validator.New()
+	if data.ValidateLanguage(v, language); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	// Replacing someone else's track needs the same rights as deleting it.
+	replaceOthers := false
+
+	existing, err := s.models.Subtitles.Get(movie.ID, language)
+	switch {
+	case err == nil:
+		if existing.CreatedBy != user.ID {
+			allowed, err := s.canModifyMovie(user, movie)
+			if err != nil {
+				s.serverErrorResponse(w, r, err)
+				return
+			}
+			if !allowed {
+				s.notPermittedResponse(w, r)
+				return
+			}
+			replaceOthers = true
+		}
+	case !errors.Is(err, data.ErrRecordNotFound):
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSubtitleBytes))
+	if err != nil {
+		s.badRequestResponse(w, r, fmt.Errorf("body must not be larger than %d bytes", maxSubtitleBytes))
+		return
+	}
+
+	if !utf8.Valid(body) {
+		s.badRequestResponse(w, r, errors.New("body must be UTF-8 encoded"))
+		return
+	}
+
+	cues, err := subtitles.Parse(body)
+	if err != nil {
+		var parseError *subtitles.ParseError
+		if errors.As(err, &parseError) {
+			for _, lineError := range parseError.Errors {
+				v.AddError(fmt.Sprintf("line %d", lineError.Line), lineError.Message)
+			}
+			s.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	subtitle := &data.Subtitle{
+		MovieID:   movie.ID,
+		Language:  language,
+		CueCount:  len(cues),
+		BlobKey:   fmt.Sprintf("subtitles/%d/%s-%d.vtt", movie.ID, language, time.Now().UnixNano()),
+		Text:      subtitles.PlainText(cues),
+		CreatedBy: user.ID,
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	err = s.blobs.Put(ctx, subtitle.BlobKey, bytes.NewReader(subtitles.WebVTT(cues)))
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	previousKey, err := s.models.Subtitles.Upsert(subtitle, replaceOthers)
+	if err != nil {
+		s.blobs.Delete(ctx, subtitle.BlobKey)
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			s.editConclictResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if previousKey != "" {
+		err = s.blobs.Delete(ctx, previousKey)
+		if err != nil {
+			s.logError(r, err)
+		}
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/movies/%d/subtitles/%s", movie.ID, language))
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"subtitle": subtitle}, headers)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) listSubtitlesHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := s.models.Subtitles.GetAllForMovie(movie.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"subtitles": list}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) showSubtitleHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	subtitle, err := s.models.Subtitles.Get(movie.ID, chi.URLParam(r, "language"))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	file, err := s.blobs.Get(r.Context(), subtitle.BlobKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, blob.ErrNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
+
+func (s *app) deleteSubtitleHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	subtitle, err := s.models.Subtitles.Get(movie.ID, chi.URLParam(r, "language"))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := s.contextGetUser(r)
+	if subtitle.CreatedBy != user.ID {
+		allowed, err := s.canModifyMovie(user, movie)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+		if !allowed {
+			s.notPermittedResponse(w, r)
+			return
+		}
+	}
+
+	err = s.models.Subtitles.Delete(subtitle.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.blobs.Delete(r.Context(), subtitle.BlobKey)
+	if err != nil {
+		s.logError(r, err)
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "subtitle successfully deleted"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteBlobs removes the blobs of deleted subtitles. The rows are already
+// gone, so failures are only logged.
+func (s *app) deleteBlobs(r *http.Request, keys []string) {
+	for _, key := range keys {
+		err := s.blobs.Delete(r.Context(), key)
+		if err != nil {
+			s.logError(r, err)
+		}
+	}
+}
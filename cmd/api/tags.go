@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *app) listMovieTagsHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	tags, err := s.models.Tags.GetAllForMovie(movie.ID, s.contextGetUser(r).ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"tags": tags}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) tagMovieHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Tags []string `json:"tags"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	tags := data.NormalizeTags(input.Tags)
+
+	v := validator.New()
+	if data.ValidateTags(v, tags); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := s.contextGetUser(r)
+
+	err = s.models.Tags.Vote(movie.ID, user.ID, tags)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	list, err := s.models.Tags.GetAllForMovie(movie.ID, user.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"tags": list}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) untagMovieHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.models.Tags.Unvote(movie.ID, s.contextGetUser(r).ID, data.NormalizeTag(chi.URLParam(r, "tag")))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "tag vote successfully removed"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) listPopularTagsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	limit := s.readInt(r.URL.Query(), "limit", 50, v)
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 200, "limit", "must be a maximum of 200")
+
+	if !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tags, err := s.models.Tags.Popular(limit)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"tags": tags}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
@@ -29,10 +29,26 @@ func (s *app) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	attempt, wait, err := s.claimLoginAttempt(r, input.Email)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if wait > 0 {
+		s.loginThrottledResponse(w, r, wait)
+		return
+	}
+
 	user, err := s.models.Users.GetByEmail(input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
+			// Take as long as a real comparison would, so that response
+			// times don't reveal which emails have an account.
+			data.DummyPasswordCheck(input.Password)
+
+			s.loginAttemptFailed(attempt, nil)
 			s.invalidCredentialsResponse(w, r)
 		default:
 			s.serverErrorResponse(w, r, err)
@@ -47,17 +63,296 @@ func (s *app) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.Re
 	}
 
 	if !match {
+		s.loginAttemptFailed(attempt, user)
 		s.invalidCredentialsResponse(w, r)
 		return
 	}
 
-	token, err := s.models.Tokens.New(user.ID, 24*time.Hour, data.ScopeAuthentication)
+	err = s.releaseLoginAttempt(attempt)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	totp, err := s.models.MFA.Get(user.ID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if totp != nil && totp.Enabled() {
+		token, err := s.models.Tokens.New(user.ID, mfaPendingTTL, data.ScopeMFAPending)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+
+		env := envelope{"mfa_token": token, "message": "enter a code from your authenticator app or a recovery code to finish signing in"}
+
+		err = s.writeJSON(w, http.StatusAccepted, env, nil)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	s.issueAuthenticationTokens(w, r, user)
+}
+
+// issueAuthenticationTokens answers a successful sign in with an access token
+// and the refresh token of a new token family.
+func (s *app) issueAuthenticationTokens(w http.ResponseWriter, r *http.Request, user *data.User) {
+	// Failures are only forgotten once both factors have been passed, so that
+	// a known password does not reset the count of wrong TOTP codes.
+	err := s.models.LoginFailures.Clear(data.LoginEmailKey(user.Email))
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	access, refresh, err := s.models.Tokens.NewFamily(user.ID, s.opaqueAccessTokenTTL(), s.config.RefreshTokenTTL, s.clientIP(r), data.TruncateUserAgent(r.UserAgent()))
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if s.config.TokenFormat == tokenFormatJWT {
+		access, err = s.signAccessToken(user, refresh.Family)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"authentication_token": access, "refresh_token": refresh}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) refreshAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TokenPlaintext string `json:"token"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, input.TokenPlaintext); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	access, refresh, err := s.models.Tokens.Rotate(input.TokenPlaintext, s.opaqueAccessTokenTTL(), s.config.RefreshTokenTTL, s.clientIP(r), data.TruncateUserAgent(r.UserAgent()))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.invalidRefreshTokenResponse(w, r)
+		case errors.Is(err, data.ErrTokenReused):
+			s.refreshTokenReusedResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if s.config.TokenFormat == tokenFormatJWT {
+		user, err := s.models.Users.Get(refresh.UserID)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+
+		access, err = s.signAccessToken(user, refresh.Family)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"authentication_token": access, "refresh_token": refresh}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) deleteAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	// A signed access token cannot be revoked, but its refresh token can, so
+	// that the client is signed out once the access token expires.
+	if family := s.contextGetSession(r); family != nil {
+		err = s.models.Tokens.DeleteFamily(family)
+	} else {
+		err = s.models.Tokens.DeleteByHash(s.contextGetToken(r))
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.invalidAuthenticationTokenResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "you have been signed out"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) deleteAllAuthenticationTokensHandler(w http.ResponseWriter, r *http.Request) {
+	user := s.contextGetUser(r)
+
+	err := s.models.Tokens.DeleteAllForUser(data.ScopeAuthentication, user.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.models.Tokens.DeleteAllForUser(data.ScopeRefresh, user.ID)
 	if err != nil {
 		s.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = s.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "you have been signed out of all sessions"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) createPasswordResetTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// The lookup happens in the background so that neither the response nor
+	// its timing tells the client whether the email address is registered.
+	s.background(func() {
+		user, err := s.models.Users.GetByEmail(input.Email)
+		if err != nil {
+			if !errors.Is(err, data.ErrRecordNotFound) {
+				s.logger.PrintError(err, nil)
+			}
+			return
+		}
+
+		if !user.Activated {
+			return
+		}
+
+		// Only the newest reset token works, and while one was sent recently
+		// no other is, so the endpoint cannot be used to flood a mailbox.
+		token, wait, err := s.models.Tokens.Reissue(user.ID, 45*time.Minute, data.ScopePasswordReset, s.config.PasswordResetCooldown)
+		if err != nil {
+			s.logger.PrintError(err, nil)
+			return
+		}
+
+		if wait > 0 {
+			return
+		}
+
+		data := map[string]interface{}{
+			"passwordResetToken": token.Plaintext,
+		}
+
+		err = s.mailer.Send(user.Email, "token_password_reset.tmpl", data)
+		if err != nil {
+			s.logger.PrintError(err, nil)
+		}
+	})
+
+	env := envelope{"message": "if that email address belongs to an activated account, you will receive an email containing password reset instructions"}
+
+	err = s.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) createActivationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := s.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("email", "no matching email address found")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if user.Activated {
+		v.AddError("email", "user has already been activated")
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	token, wait, err := s.models.Tokens.Reissue(user.ID, 3*24*time.Hour, data.ScopeActivation, s.config.ActivationCooldown)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if wait > 0 {
+		s.activationCooldownResponse(w, r, wait)
+		return
+	}
+
+	s.background(func() {
+		data := map[string]interface{}{
+			"activationToken": token.Plaintext,
+			"userID":          user.ID,
+		}
+
+		err := s.mailer.Send(user.Email, "user_welcome.tmpl", data)
+		if err != nil {
+			s.logger.PrintError(err, nil)
+		}
+	})
+
+	env := envelope{"message": "an email will be sent to you containing activation instructions"}
+
+	err = s.writeJSON(w, http.StatusAccepted, env, nil)
 	if err != nil {
 		s.serverErrorResponse(w, r, err)
 	}
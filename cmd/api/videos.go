@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/validator"
+	"github.com/SemmiDev/chimovies/internal/videos"
+)
+
+// videoCheckConcurrency is how many links of a movie are probed at once.
+const videoCheckConcurrency = 8
+
+func (s *app) createVideoHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Kind     string `json:"kind"`
+		Provider string `json:"provider"`
+		URL      string `json:"url"`
+		Language string `json:"language"`
+		Duration int32  `json:"duration"`
+		Official bool   `json:"official"`
+	}
+
+	err := s.readJSON(w, r, &input)
+	if err != nil {
+		s.badRequestResponse(w, r, err)
+		return
+	}
+
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	allowed, err := s.canModifyMovie(s.contextGetUser(r), movie)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		s.notPermittedResponse(w, r)
+		return
+	}
+
+	video := &data.Video{
+		MovieID:  movie.ID,
+		Kind:     input.Kind,
+		Provider: strings.ToLower(input.Provider),
+		URL:      input.URL,
+		Language: input.Language,
+		Duration: input.Duration,
+		Official: input.Official,
+	}
+
+	v := validator.New()
+	if data.ValidateVideo(v, video); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	video.URL, err = videos.EmbedURL(video.Provider, video.URL)
+	if err != nil {
+		v.AddError("url", err.Error())
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = s.models.Videos.Insert(video)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateName):
+			v.AddError("url", "this video has already been added to the movie")
+			s.failedValidationResponse(w, r, v.Errors)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/movies/%d/videos", movie.ID))
+
+	err = s.writeJSON(w, http.StatusCreated, envelope{"video": video}, headers)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) listVideosHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := s.models.Videos.GetAllForMovie(movie.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"videos": list}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+func (s *app) deleteVideoHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := s.readIDParam(r)
+	if err != nil {
+		s.notFoundResponse(w, r)
+		return
+	}
+
+	video, err := s.models.Videos.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	movie, err := s.models.Movies.Get(video.MovieID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	allowed, err := s.canModifyMovie(s.contextGetUser(r), movie)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		s.notPermittedResponse(w, r)
+		return
+	}
+
+	err = s.models.Videos.Delete(video.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			s.notFoundResponse(w, r)
+		default:
+			s.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"message": "video successfully deleted"}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkVideosHandler probes every video link of the movie concurrently and
+// stores the result on each video.
+func (s *app) checkVideosHandler(w http.ResponseWriter, r *http.Request) {
+	movie, ok := s.readVisibleMovie(w, r)
+	if !ok {
+		return
+	}
+
+	allowed, err := s.canModifyMovie(s.contextGetUser(r), movie)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		s.notPermittedResponse(w, r)
+		return
+	}
+
+	list, err := s.models.Videos.GetAllForMovie(movie.ID)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	sem := make(chan struct{}, videoCheckConcurrency)
+
+	var wg sync.WaitGroup
+	for _, video := range list {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(video *data.Video) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			video.Health = s.links.Check(ctx, video.URL)
+		}(video)
+	}
+	wg.Wait()
+
+	for _, video := range list {
+		err = s.models.Videos.UpdateHealth(video)
+		if err != nil {
+			s.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"videos": list}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
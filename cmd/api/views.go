@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/data"
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+const viewRetention = 30 * 24 * time.Hour
+
+var trendingWindows = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+type viewCounter struct {
+	mu     sync.Mutex
+	counts map[int64]*data.ViewCount
+}
+
+func newViewCounter() *viewCounter {
+	return &viewCounter{counts: make(map[int64]*data.ViewCount)}
+}
+
+func (c *viewCounter) count(id int64) *data.ViewCount {
+	if _, found := c.counts[id]; !found {
+		c.counts[id] = &data.ViewCount{}
+	}
+	return c.counts[id]
+}
+
+func (c *viewCounter) view(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.count(id).Views++
+}
+
+func (c *viewCounter) hit(ids ...int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range ids {
+		c.count(id).Hits++
+	}
+}
+
+func (c *viewCounter) drain() map[int64]*data.ViewCount {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := c.counts
+	c.counts = make(map[int64]*data.ViewCount)
+	return counts
+}
+
+func (c *viewCounter) restore(counts map[int64]*data.ViewCount) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, count := range counts {
+		c.count(id).Views += count.Views
+		c.count(id).Hits += count.Hits
+	}
+}
+
+func (s *app) flushViews() error {
+	counts := s.views.drain()
+
+	err := s.models.Views.Add(time.Now().Truncate(time.Hour), counts)
+	if err != nil {
+		s.views.restore(counts)
+		return err
+	}
+	return nil
+}
+
+func (s *app) pruneViews() error {
+	return s.models.Views.DeleteBefore(time.Now().Add(-viewRetention).Truncate(time.Hour))
+}
+
+func (s *app) listTrendingMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Window string
+		data.Filters
+		RuntimeFormat data.RuntimeFormat
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Window = s.readString(qs, "window", "24h")
+	input.Filters.Page = s.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = s.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = s.readString(qs, "sort", "-views")
+	input.Filters.SortSafelist = []string{"-views"}
+	input.RuntimeFormat = s.readRuntimeFormat(qs, v)
+
+	window, found := trendingWindows[input.Window]
+	v.Check(found, "window", "must be one of 24h, 7d or 30d")
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		s.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movies, metadata, err := s.models.Views.Trending(time.Now().Add(-window), input.Filters)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, trending := range movies {
+		trending.Movie.RuntimeFormat = input.RuntimeFormat
+	}
+
+	err = s.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
+	if err != nil {
+		s.serverErrorResponse(w, r, err)
+	}
+}
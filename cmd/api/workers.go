@@ -0,0 +1,33 @@
+package main
+
+import (
+	"time"
+)
+
+func (s *app) startWorkers() {
+	s.every(time.Minute, s.publishScheduledMovies)
+	s.every(s.config.ViewsFlushInterval, s.flushViews)
+	s.every(time.Hour, s.pruneViews)
+	s.every(time.Minute, s.releaseExpiredHolds)
+	s.every(s.config.SessionFlushInterval, s.flushSessions)
+	s.every(s.config.SessionFlushInterval, s.flushAPIKeys)
+	s.every(time.Hour, s.pruneLoginFailures)
+
+	if s.config.TokenFormat == tokenFormatJWT {
+		s.every(signingKeyReload, s.rotateSigningKeys)
+	}
+}
+
+func (s *app) every(interval time.Duration, fn func() error) {
+	s.background(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			err := fn()
+			if err != nil {
+				s.logger.PrintError(err, nil)
+			}
+		}
+	})
+}
@@ -29,7 +29,42 @@ type Config struct {
 	Password string `mapstructure:"SMTP_PASSWORD"`
 	Sender   string `mapstructure:"SMTP_SENDER"`
 
+	SimilarWeightGenres  float64       `mapstructure:"SIMILAR_WEIGHT_GENRES"`
+	SimilarWeightYear    float64       `mapstructure:"SIMILAR_WEIGHT_YEAR"`
+	SimilarWeightRuntime float64       `mapstructure:"SIMILAR_WEIGHT_RUNTIME"`
+	SimilarCacheTTL      time.Duration `mapstructure:"SIMILAR_CACHE_TTL"`
+	SimilarCacheSize     int           `mapstructure:"SIMILAR_CACHE_SIZE"`
+
+	ViewsFlushInterval time.Duration `mapstructure:"VIEWS_FLUSH_INTERVAL"`
+
+	BlobDir string `mapstructure:"BLOB_DIR"`
+
+	ShowtimeCleaningBuffer time.Duration `mapstructure:"SHOWTIME_CLEANING_BUFFER"`
+	HoldTTL                time.Duration `mapstructure:"HOLD_TTL"`
+
+	LinkCheckTimeout time.Duration `mapstructure:"LINK_CHECK_TIMEOUT"`
+
+	ActivationCooldown    time.Duration `mapstructure:"ACTIVATION_COOLDOWN"`
+	PasswordResetCooldown time.Duration `mapstructure:"PASSWORD_RESET_COOLDOWN"`
+	AccessTokenTTL        time.Duration `mapstructure:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL       time.Duration `mapstructure:"REFRESH_TOKEN_TTL"`
+	SessionFlushInterval  time.Duration `mapstructure:"SESSION_FLUSH_INTERVAL"`
+
+	LoginMaxFailures      int           `mapstructure:"LOGIN_MAX_FAILURES"`
+	LoginMaxFailuresPerIP int           `mapstructure:"LOGIN_MAX_FAILURES_PER_IP"`
+	LoginLockout          time.Duration `mapstructure:"LOGIN_LOCKOUT"`
+	LoginBackoff          time.Duration `mapstructure:"LOGIN_BACKOFF"`
+
+	TokenFormat    string        `mapstructure:"TOKEN_FORMAT"`
+	JWTAlgorithm   string        `mapstructure:"JWT_ALGORITHM"`
+	JWTIssuer      string        `mapstructure:"JWT_ISSUER"`
+	JWTKeyRotation time.Duration `mapstructure:"JWT_KEY_ROTATION"`
+
 	TrustedOrigins []string
+
+	// TrustedProxies lists the addresses or CIDR blocks of the reverse
+	// proxies whose X-Forwarded-For and X-Real-Ip headers are believed.
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
 }
 
 func LoadConfig(path string) (cfg Config) {
@@ -0,0 +1,306 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/validator"
+	"github.com/lib/pq"
+)
+
+// APIKeyPrefix marks API keys so that they can be told apart from other
+// bearer tokens, and spotted by secret scanners when they leak.
+const APIKeyPrefix = "chm_live_"
+
+// apiKeyDisplayLength is how much of a key is kept in the clear, so that
+// users can recognise their keys in a listing.
+const apiKeyDisplayLength = len(APIKeyPrefix) + 6
+
+type APIKey struct {
+	ID          int64       `json:"id"`
+	UserID      int64       `json:"-"`
+	Name        string      `json:"name"`
+	Plaintext   string      `json:"key,omitempty"`
+	Prefix      string      `json:"prefix"`
+	Hash        []byte      `json:"-"`
+	Permissions Permissions `json:"permissions"`
+	AllowedIPs  []string    `json:"allowed_ips"`
+	Expiry      *time.Time  `json:"expiry,omitempty"`
+	LastUsedAt  *time.Time  `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+func generateAPIKey(key *APIKey) error {
+	randomBytes := make([]byte, 20)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return err
+	}
+
+	key.Plaintext = APIKeyPrefix + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes))
+	key.Prefix = key.Plaintext[:apiKeyDisplayLength]
+	key.Hash = HashToken(key.Plaintext)
+
+	return nil
+}
+
+// ValidateAPIKey checks the key against the permissions of the user creating
+// it, since a key can only narrow what its owner may do.
+func ValidateAPIKey(v *validator.Validator, key *APIKey, granted Permissions) {
+	v.Check(key.Name != "", "name", "must be provided")
+	v.Check(len(key.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(len(key.Permissions) >= 1, "permissions", "must contain at least 1 permission")
+	v.Check(validator.Unique(key.Permissions), "permissions", "must not contain duplicate values")
+	for _, code := range key.Permissions {
+		if !granted.Include(code) {
+			v.AddError("permissions", "must only contain permissions you have, "+code+" is not one of them")
+			break
+		}
+	}
+
+	v.Check(len(key.AllowedIPs) <= 20, "allowed_ips", "must not contain more than 20 entries")
+	for _, entry := range key.AllowedIPs {
+		if _, err := ParseIPNet(entry); err != nil {
+			v.AddError("allowed_ips", "must contain IP addresses or CIDR ranges, "+entry+" is neither")
+			break
+		}
+	}
+
+	if key.Expiry != nil {
+		v.Check(key.Expiry.After(time.Now()), "expiry", "must be in the future")
+	}
+}
+
+// ParseIPNet parses a CIDR block, or a single address as a block containing
+// only that address.
+func ParseIPNet(entry string) (*net.IPNet, error) {
+	if !strings.Contains(entry, "/") {
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, errors.New("invalid IP address")
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, ipNet, err := net.ParseCIDR(entry)
+	return ipNet, err
+}
+
+// AllowsIP reports whether the key may be used from the given address. A key
+// without an allowlist may be used from anywhere.
+func (k *APIKey) AllowsIP(address string) bool {
+	if len(k.AllowedIPs) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, entry := range k.AllowedIPs {
+		ipNet, err := ParseIPNet(entry)
+		if err == nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+type APIKeyModel struct {
+	DB *sql.DB
+}
+
+func (m APIKeyModel) Insert(key *APIKey) error {
+	err := generateAPIKey(key)
+	if err != nil {
+		return err
+	}
+
+	if key.AllowedIPs == nil {
+		key.AllowedIPs = []string{}
+	}
+
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, hash, permissions, allowed_ips, expiry)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	args := []interface{}{key.UserID, key.Name, key.Prefix, key.Hash, pq.Array([]string(key.Permissions)), pq.Array(key.AllowedIPs), key.Expiry}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&key.ID, &key.CreatedAt)
+}
+
+func (m APIKeyModel) GetAllForUser(userID int64) ([]*APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, permissions, allowed_ips, expiry, last_used_at, created_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+
+	for rows.Next() {
+		var key APIKey
+
+		err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			&key.Prefix,
+			pq.Array((*[]string)(&key.Permissions)),
+			pq.Array(&key.AllowedIPs),
+			&key.Expiry,
+			&key.LastUsedAt,
+			&key.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, &key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// GetForKey looks up an unexpired key and its owner. The key's permissions
+// are narrowed to the ones its owner still holds.
+func (m APIKeyModel) GetForKey(plaintext string) (*APIKey, *User, error) {
+	query := `
+		SELECT k.id, k.name, k.prefix, k.allowed_ips, k.expiry, k.last_used_at, k.created_at,
+			ARRAY(
+				SELECT p.code
+				FROM permissions p
+				INNER JOIN users_permissions up ON up.permission_id = p.id
+				WHERE up.user_id = k.user_id AND p.code = ANY(k.permissions)),
+			u.id, u.created_at, u.name, u.email, u.activated, u.version
+		FROM api_keys k
+		INNER JOIN users u ON u.id = k.user_id
+		WHERE k.hash = $1
+		AND (k.expiry IS NULL OR k.expiry > NOW())`
+
+	var (
+		key  APIKey
+		user User
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, HashToken(plaintext)).Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		pq.Array(&key.AllowedIPs),
+		&key.Expiry,
+		&key.LastUsedAt,
+		&key.CreatedAt,
+		pq.Array((*[]string)(&key.Permissions)),
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Activated,
+		&user.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+
+	key.UserID = user.ID
+	if key.Permissions == nil {
+		key.Permissions = Permissions{}
+	}
+
+	return &key, &user, nil
+}
+
+// Touch records when each key, keyed by its ID, was last used.
+func (m APIKeyModel) Touch(used map[int64]time.Time) error {
+	if len(used) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(used))
+	times := make([]int64, 0, len(used))
+	for id, usedAt := range used {
+		ids = append(ids, id)
+		times = append(times, usedAt.Unix())
+	}
+
+	query := `
+		UPDATE api_keys
+		SET last_used_at = to_timestamp(u.used_at)
+		FROM unnest($1::bigint[], $2::bigint[]) AS u(id, used_at)
+		WHERE api_keys.id = u.id
+		AND (api_keys.last_used_at IS NULL OR api_keys.last_used_at < to_timestamp(u.used_at))`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, pq.Array(ids), pq.Array(times))
+	return err
+}
+
+func (m APIKeyModel) Delete(id, userID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM api_keys
+		WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
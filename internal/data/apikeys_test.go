@@ -0,0 +1,70 @@
+package data
+
+import (
+	"testing"
+)
+
+func TestParseIPNet(t *testing.T) {
+	tests := []struct {
+		entry string
+		want  string
+	}{
+		{"203.0.113.7", "203.0.113.7/32"},
+		{"203.0.113.0/24", "203.0.113.0/24"},
+		{"203.0.113.7/24", "203.0.113.0/24"},
+		{"2001:db8::1", "2001:db8::1/128"},
+		{"2001:db8::/32", "2001:db8::/32"},
+		{"::ffff:203.0.113.7", "203.0.113.7/32"},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseIPNet(tt.entry)
+		if err != nil {
+			t.Errorf("ParseIPNet(%q): unexpected error: %v", tt.entry, err)
+			continue
+		}
+
+		if got.String() != tt.want {
+			t.Errorf("ParseIPNet(%q) = %s; want %s", tt.entry, got, tt.want)
+		}
+	}
+
+	for _, entry := range []string{"", "localhost", "203.0.113", "203.0.113.0/33", "2001:db8::/129", "/24"} {
+		if _, err := ParseIPNet(entry); err == nil {
+			t.Errorf("ParseIPNet(%q): expected an error", entry)
+		}
+	}
+}
+
+func TestAPIKeyAllowsIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		address string
+		want    bool
+	}{
+		{"empty allowlist", nil, "198.51.100.1", true},
+		{"empty allowlist unparseable address", []string{}, "not-an-ip", true},
+		{"single address", []string{"203.0.113.7"}, "203.0.113.7", true},
+		{"single address other", []string{"203.0.113.7"}, "203.0.113.8", false},
+		{"cidr inside", []string{"203.0.113.0/24"}, "203.0.113.200", true},
+		{"cidr outside", []string{"203.0.113.0/24"}, "203.0.114.1", false},
+		{"second entry", []string{"198.51.100.0/24", "203.0.113.0/24"}, "203.0.113.1", true},
+		{"ipv6 address", []string{"2001:db8::1"}, "2001:db8::1", true},
+		{"ipv6 cidr", []string{"2001:db8::/32"}, "2001:db8:ffff::1", true},
+		{"ipv6 outside", []string{"2001:db8::/32"}, "2001:db9::1", false},
+		{"ipv4-mapped client", []string{"203.0.113.0/24"}, "::ffff:203.0.113.7", true},
+		{"ipv4-mapped entry", []string{"::ffff:203.0.113.7"}, "203.0.113.7", true},
+		{"ipv4 entry ipv6 client", []string{"203.0.113.0/24"}, "2001:db8::1", false},
+		{"unparseable address", []string{"203.0.113.0/24"}, "not-an-ip", false},
+		{"address with port", []string{"203.0.113.0/24"}, "203.0.113.7:443", false},
+		{"empty address", []string{"203.0.113.0/24"}, "", false},
+	}
+
+	for _, tt := range tests {
+		key := APIKey{AllowedIPs: tt.allowed}
+		if got := key.AllowsIP(tt.address); got != tt.want {
+			t.Errorf("%s: AllowsIP(%q) = %t; want %t", tt.name, tt.address, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/validator"
+	"github.com/lib/pq"
+)
+
+type AwardEvent struct {
+	ID         int64            `json:"id"`
+	Name       string           `json:"name"`
+	Year       int32            `json:"year"`
+	HeldOn     *Date            `json:"held_on,omitempty"`
+	Categories []*AwardCategory `json:"categories,omitempty"`
+	Version    int32            `json:"version"`
+	CreatedAt  time.Time        `json:"-"`
+}
+
+type AwardCategory struct {
+	ID          int64         `json:"id"`
+	Name        string        `json:"name"`
+	Nominations []*Nomination `json:"nominations"`
+}
+
+// Nomination links a movie, and optionally a named person, to an award
+// category. The event and category fields are only filled in when listing the
+// nominations of a movie.
+type Nomination struct {
+	ID         int64  `json:"id"`
+	EventID    int64  `json:"event_id,omitempty"`
+	EventName  string `json:"event_name,omitempty"`
+	EventYear  int32  `json:"event_year,omitempty"`
+	Category   string `json:"category,omitempty"`
+	MovieID    int64  `json:"movie_id"`
+	MovieTitle string `json:"movie_title,omitempty"`
+	Nominee    string `json:"nominee,omitempty"`
+	Won        bool   `json:"won"`
+}
+
+func ValidateAwardEvent(v *validator.Validator, event *AwardEvent) {
+	v.Check(event.Name != "", "name", "must be provided")
+	v.Check(len(event.Name) <= 200, "name", "must not be more than 200 bytes long")
+
+	v.Check(event.Year != 0, "year", "must be provided")
+	v.Check(event.Year >= 1888, "year", "must be greater than 1888")
+	v.Check(event.Year <= int32(time.Now().Year()+1), "year", "must not be more than a year in the future")
+
+	if event.HeldOn != nil {
+		v.Check(event.HeldOn.Year() >= 1888, "held_on", "must be after 1888")
+	}
+}
+
+// ValidateNomination records the first problem with the nomination under key,
+// so that every line of an import can be reported at once.
+func ValidateNomination(v *validator.Validator, key string, nomination *Nomination) {
+	v.Check(nomination.Category != "", key, "category must be provided")
+	v.Check(len(nomination.Category) <= 200, key, "category must not be more than 200 bytes long")
+	v.Check(nomination.MovieID > 0, key, "movie_id must be a positive integer")
+	v.Check(len(nomination.Nominee) <= 500, key, "nominee must not be more than 500 bytes long")
+}
+
+type AwardModel struct {
+	DB *sql.DB
+}
+
+func (m *AwardModel) InsertEvent(event *AwardEvent) error {
+	query := `
+		INSERT INTO award_events (name, year, held_on)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, version`
+
+	args := []interface{}{event.Name, event.Year, event.heldOn()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&event.ID, &event.CreatedAt, &event.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "award_events_name_year_key"):
+			return ErrDuplicateName
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// GetEvent returns the event with its categories and the nominations of
+// published movies in each of them.
+func (m *AwardModel) GetEvent(id int64) (*AwardEvent, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, created_at, name, year, held_on, version
+		FROM award_events
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	event, err := scanAwardEvent(m.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	query = `
+		SELECT c.id, c.name, n.id, n.movie_id, m.title, n.nominee, n.won
+		FROM award_categories c
+		INNER JOIN award_nominations n ON n.category_id = c.id
+		INNER JOIN movies m ON m.id = n.movie_id
+		WHERE c.event_id = $1
+		AND m.status = 'published'
+		ORDER BY c.name, n.won DESC, n.id`
+
+	rows, err := m.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	event.Categories = []*AwardCategory{}
+
+	var category *AwardCategory
+	for rows.Next() {
+		var (
+			categoryID   int64
+			categoryName string
+			nomination   Nomination
+		)
+
+		err := rows.Scan(
+			&categoryID,
+			&categoryName,
+			&nomination.ID,
+			&nomination.MovieID,
+			&nomination.MovieTitle,
+			&nomination.Nominee,
+			&nomination.Won,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if category == nil || category.ID != categoryID {
+			category = &AwardCategory{ID: categoryID, Name: categoryName}
+			event.Categories = append(event.Categories, category)
+		}
+		category.Nominations = append(category.Nominations, &nomination)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func (m *AwardModel) DeleteEvent(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM award_events WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *AwardModel) GetAllEvents(name string, year int, filters Filters) ([]*AwardEvent, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, name, year, held_on, version
+	FROM award_events
+	WHERE (lower(name) = lower($1) OR $1 = '')
+	AND (year = $2 OR $2 = 0)
+	ORDER BY %s %s, id ASC
+	LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, name, year, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	events := []*AwardEvent{}
+
+	for rows.Next() {
+		var (
+			event  AwardEvent
+			heldOn sql.NullTime
+		)
+
+		err := rows.Scan(&totalRecords, &event.ID, &event.CreatedAt, &event.Name, &event.Year, &heldOn, &event.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		if heldOn.Valid {
+			event.HeldOn = &Date{Time: heldOn.Time}
+		}
+
+		events = append(events, &event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return events, metadata, nil
+}
+
+// MissingMovies returns the ids among ids that do not refer to a movie.
+func (m *AwardModel) MissingMovies(ids []int64) ([]int64, error) {
+	query := `
+		SELECT id
+		FROM unnest($1::bigint[]) AS ids(id)
+		WHERE NOT EXISTS (SELECT 1 FROM movies WHERE movies.id = ids.id)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	missing := []int64{}
+	for rows.Next() {
+		var id int64
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		missing = append(missing, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return missing, nil
+}
+
+// ImportNominations adds the nominations to the event in a single
+// transaction, creating categories as needed. Importing the same nomination
+// again only updates its won flag, so a file can be re-imported once the
+// winners are known.
+func (m *AwardModel) ImportNominations(eventID int64, nominations []*Nomination) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	categories := make(map[string]int64)
+
+	for _, nomination := range nominations {
+		categoryID, ok := categories[nomination.Category]
+		if !ok {
+			query := `
+				INSERT INTO award_categories (event_id, name)
+				VALUES ($1, $2)
+				ON CONFLICT (event_id, name) DO UPDATE SET name = EXCLUDED.name
+				RETURNING id`
+
+			err = tx.QueryRowContext(ctx, query, eventID, nomination.Category).Scan(&categoryID)
+			if err != nil {
+				return err
+			}
+			categories[nomination.Category] = categoryID
+		}
+
+		query := `
+			INSERT INTO award_nominations (category_id, movie_id, nominee, won)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (category_id, movie_id, nominee) DO UPDATE SET won = EXCLUDED.won
+			RETURNING id`
+
+		err = tx.QueryRowContext(ctx, query, categoryID, nomination.MovieID, nomination.Nominee, nomination.Won).Scan(&nomination.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m *AwardModel) GetAllForMovie(movieID int64) ([]*Nomination, error) {
+	query := `
+		SELECT n.id, e.id, e.name, e.year, c.name, n.movie_id, n.nominee, n.won
+		FROM award_nominations n
+		INNER JOIN award_categories c ON c.id = n.category_id
+		INNER JOIN award_events e ON e.id = c.event_id
+		WHERE n.movie_id = $1
+		ORDER BY e.year DESC, e.name, c.name, n.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nominations := []*Nomination{}
+
+	for rows.Next() {
+		var nomination Nomination
+
+		err := rows.Scan(
+			&nomination.ID,
+			&nomination.EventID,
+			&nomination.EventName,
+			&nomination.EventYear,
+			&nomination.Category,
+			&nomination.MovieID,
+			&nomination.Nominee,
+			&nomination.Won,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		nominations = append(nominations, &nomination)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return nominations, nil
+}
+
+func (e *AwardEvent) heldOn() *time.Time {
+	if e.HeldOn == nil {
+		return nil
+	}
+	return &e.HeldOn.Time
+}
+
+func scanAwardEvent(row rowScanner) (*AwardEvent, error) {
+	var (
+		event  AwardEvent
+		heldOn sql.NullTime
+	)
+
+	err := row.Scan(&event.ID, &event.CreatedAt, &event.Name, &event.Year, &heldOn, &event.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	if heldOn.Valid {
+		event.HeldOn = &Date{Time: heldOn.Time}
+	}
+
+	return &event, nil
+}
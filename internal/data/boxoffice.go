@@ -0,0 +1,334 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+type Gross struct {
+	Region    string    `json:"region"`
+	Gross     Money     `json:"gross"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type BoxOffice struct {
+	MovieID    int64    `json:"movie_id"`
+	Budget     *Money   `json:"budget"`
+	Grosses    []*Gross `json:"grosses"`
+	TotalGross *Money   `json:"total_gross,omitempty"`
+	Complete   bool     `json:"complete"`
+}
+
+// Total sums the regional grosses in the given currency. Grosses in currencies
+// without an exchange rate are left out and reported through Complete.
+func (b *BoxOffice) Total(rates Rates, currency string) error {
+	total := Money{Currency: currency}
+	b.Complete = true
+
+	for _, gross := range b.Grosses {
+		converted, err := rates.Convert(gross.Gross, currency)
+		if err != nil {
+			if errors.Is(err, ErrMissingRate) {
+				b.Complete = false
+				continue
+			}
+			return err
+		}
+		total.Amount += converted.Amount
+	}
+
+	b.TotalGross = &total
+	return nil
+}
+
+type LeaderboardEntry struct {
+	MovieID  int64  `json:"movie_id"`
+	Title    string `json:"title"`
+	Year     int32  `json:"year"`
+	Gross    Money  `json:"gross"`
+	Complete bool   `json:"complete"`
+}
+
+func ValidateMoney(v *validator.Validator, key string, money Money) {
+	ValidateCurrency(v, key, money.Currency)
+	v.Check(money.Amount >= 0, key, "must not be negative")
+}
+
+type BoxOfficeModel struct {
+	DB *sql.DB
+}
+
+func (m BoxOfficeModel) Get(movieID int64) (*BoxOffice, error) {
+	query := `
+		SELECT budget_amount, budget_currency
+		FROM movies
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var (
+		budgetAmount   sql.NullInt64
+		budgetCurrency sql.NullString
+	)
+
+	err := m.DB.QueryRowContext(ctx, query, movieID).Scan(&budgetAmount, &budgetCurrency)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	boxOffice := &BoxOffice{MovieID: movieID, Grosses: []*Gross{}}
+	if budgetAmount.Valid {
+		boxOffice.Budget = &Money{Amount: budgetAmount.Int64, Currency: budgetCurrency.String}
+	}
+
+	query = `
+		SELECT region, gross_amount, currency, updated_at
+		FROM box_office
+		WHERE movie_id = $1
+		ORDER BY region`
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var gross Gross
+
+		err := rows.Scan(&gross.Region, &gross.Gross.Amount, &gross.Gross.Currency, &gross.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		boxOffice.Grosses = append(boxOffice.Grosses, &gross)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return boxOffice, nil
+}
+
+// SetBudget stores the movie's budget, or clears it when budget is nil.
+func (m BoxOfficeModel) SetBudget(movieID int64, budget *Money) error {
+	query := `
+		UPDATE movies
+		SET budget_amount = $2, budget_currency = $3
+		WHERE id = $1`
+
+	var (
+		amount   sql.NullInt64
+		currency sql.NullString
+	)
+	if budget != nil {
+		amount = sql.NullInt64{Int64: budget.Amount, Valid: true}
+		currency = sql.NullString{String: budget.Currency, Valid: true}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, movieID, amount, currency)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m BoxOfficeModel) UpsertGross(movieID int64, gross *Gross) error {
+	query := `
+		INSERT INTO box_office (movie_id, region, gross_amount, currency)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (movie_id, region) DO UPDATE
+		SET gross_amount = EXCLUDED.gross_amount, currency = EXCLUDED.currency, updated_at = NOW()
+		RETURNING updated_at`
+
+	args := []interface{}{movieID, gross.Region, gross.Gross.Amount, gross.Gross.Currency}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&gross.UpdatedAt)
+}
+
+func (m BoxOfficeModel) DeleteGross(movieID int64, region string) error {
+	query := `DELETE FROM box_office WHERE movie_id = $1 AND region = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, movieID, region)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Leaderboard ranks the published movies of a year by their worldwide gross
+// in the reporting currency.
+func (m BoxOfficeModel) Leaderboard(year int, filters Filters) ([]*LeaderboardEntry, Metadata, error) {
+	query := `
+		SELECT count(*) OVER(), movies.id, movies.title, movies.year, movie_gross_usd.gross_usd, movie_gross_usd.complete
+		FROM movies
+		INNER JOIN movie_gross_usd ON movie_gross_usd.movie_id = movies.id
+		WHERE movies.year = $1
+		AND movies.status = 'published'
+		AND movie_gross_usd.gross_usd > 0
+		ORDER BY movie_gross_usd.gross_usd DESC, movies.id ASC
+		LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, year, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*LeaderboardEntry{}
+
+	for rows.Next() {
+		entry := LeaderboardEntry{Gross: Money{Currency: ReportingCurrency}}
+
+		err := rows.Scan(&totalRecords, &entry.MovieID, &entry.Title, &entry.Year, &entry.Gross.Amount, &entry.Complete)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
+
+type ExchangeRate struct {
+	Currency  string    `json:"currency"`
+	Rate      string    `json:"rate"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func ValidateExchangeRate(v *validator.Validator, rate *ExchangeRate) {
+	ValidateCurrency(v, "currency", rate.Currency)
+	v.Check(rate.Currency != ReportingCurrency, "currency", "must not be the reporting currency "+ReportingCurrency)
+
+	r, ok := new(big.Rat).SetString(rate.Rate)
+	v.Check(ok && validator.Matches(rate.Rate, amountRX), "rate", "must be a decimal number")
+	v.Check(ok && r.Sign() > 0, "rate", "must be greater than zero")
+}
+
+type ExchangeRateModel struct {
+	DB *sql.DB
+}
+
+// Upsert stores the value of one major unit of the currency in the reporting
+// currency. The currency's exponent is stored alongside it so that the
+// movie_gross_usd view can convert minor units without knowing ISO 4217.
+func (m ExchangeRateModel) Upsert(rate *ExchangeRate) error {
+	exponent, _ := CurrencyExponent(rate.Currency)
+
+	query := `
+		INSERT INTO exchange_rates (currency, rate, exponent)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (currency) DO UPDATE
+		SET rate = EXCLUDED.rate, exponent = EXCLUDED.exponent, updated_at = NOW()
+		RETURNING rate::text, updated_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, rate.Currency, rate.Rate, exponent).Scan(&rate.Rate, &rate.UpdatedAt)
+}
+
+func (m ExchangeRateModel) GetAll() ([]*ExchangeRate, error) {
+	query := `
+		SELECT currency, rate::text, updated_at
+		FROM exchange_rates
+		ORDER BY currency`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []*ExchangeRate{}
+
+	for rows.Next() {
+		var rate ExchangeRate
+
+		err := rows.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		rates = append(rates, &rate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+func (m ExchangeRateModel) Rates() (Rates, error) {
+	list, err := m.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make(Rates, len(list))
+	for _, rate := range list {
+		r, ok := new(big.Rat).SetString(rate.Rate)
+		if !ok {
+			continue
+		}
+		rates[rate.Currency] = r
+	}
+
+	return rates, nil
+}
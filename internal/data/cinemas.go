@@ -0,0 +1,259 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+var CountryRX = regexp.MustCompile(`^[A-Z]{2}$`)
+
+var ErrUnknownTimezone = errors.New("unknown time zone")
+
+type Cinema struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	City      string    `json:"city"`
+	Country   string    `json:"country"`
+	Timezone  string    `json:"timezone"`
+	Screens   []*Screen `json:"screens,omitempty"`
+	Version   int32     `json:"version"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// Location returns the cinema's time zone, falling back to UTC for zones the
+// host does not know about.
+func (c *Cinema) Location() *time.Location {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func ValidateCinema(v *validator.Validator, cinema *Cinema) {
+	v.Check(cinema.Name != "", "name", "must be provided")
+	v.Check(len(cinema.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	v.Check(cinema.Address != "", "address", "must be provided")
+	v.Check(len(cinema.Address) <= 500, "address", "must not be more than 500 bytes long")
+
+	v.Check(cinema.City != "", "city", "must be provided")
+	v.Check(len(cinema.City) <= 200, "city", "must not be more than 200 bytes long")
+
+	v.Check(validator.Matches(cinema.Country, CountryRX), "country", "must be an ISO 3166-1 alpha-2 code such as ID or US")
+
+	// LoadLocation maps "" to UTC and "Local" to the host's zone, neither of
+	// which names a place.
+	v.Check(cinema.Timezone != "", "timezone", "must be provided")
+	if cinema.Timezone != "" {
+		_, err := time.LoadLocation(cinema.Timezone)
+		v.Check(err == nil && cinema.Timezone != "Local", "timezone", "must be an IANA time zone such as Asia/Jakarta")
+	}
+}
+
+type CinemaModel struct {
+	DB *sql.DB
+}
+
+// checkTimezone returns ErrUnknownTimezone when the database does not know
+// the zone, since showtime queries convert times with AT TIME ZONE and would
+// fail for every cinema listed alongside it.
+func (m *CinemaModel) checkTimezone(ctx context.Context, timezone string) error {
+	query := `SELECT EXISTS (SELECT 1 FROM pg_timezone_names WHERE name = $1)`
+
+	var known bool
+
+	err := m.DB.QueryRowContext(ctx, query, timezone).Scan(&known)
+	if err != nil {
+		return err
+	}
+
+	if !known {
+		return ErrUnknownTimezone
+	}
+
+	return nil
+}
+
+func (m *CinemaModel) Insert(cinema *Cinema) error {
+	query := `
+		INSERT INTO cinemas (name, address, city, country, timezone)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, version`
+
+	args := []interface{}{cinema.Name, cinema.Address, cinema.City, cinema.Country, cinema.Timezone}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.checkTimezone(ctx, cinema.Timezone)
+	if err != nil {
+		return err
+	}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&cinema.ID, &cinema.CreatedAt, &cinema.Version)
+}
+
+func (m *CinemaModel) Get(id int64) (*Cinema, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, created_at, name, address, city, country, timezone, version
+		FROM cinemas
+		WHERE id = $1`
+
+	var cinema Cinema
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&cinema.ID,
+		&cinema.CreatedAt,
+		&cinema.Name,
+		&cinema.Address,
+		&cinema.City,
+		&cinema.Country,
+		&cinema.Timezone,
+		&cinema.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &cinema, nil
+}
+
+func (m *CinemaModel) Update(cinema *Cinema) error {
+	query := `
+		UPDATE cinemas
+		SET name = $1, address = $2, city = $3, country = $4, timezone = $5, version = version + 1
+		WHERE id = $6 AND version = $7
+		RETURNING version`
+
+	args := []interface{}{
+		cinema.Name,
+		cinema.Address,
+		cinema.City,
+		cinema.Country,
+		cinema.Timezone,
+		cinema.ID,
+		cinema.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.checkTimezone(ctx, cinema.Timezone)
+	if err != nil {
+		return err
+	}
+
+	err = m.DB.QueryRowContext(ctx, query, args...).Scan(&cinema.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *CinemaModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM cinemas WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *CinemaModel) GetAll(name, city, country string, filters Filters) ([]*Cinema, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, name, address, city, country, timezone, version
+	FROM cinemas
+	WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
+	AND (lower(city) = lower($2) OR $2 = '')
+	AND (country = $3 OR $3 = '')
+	ORDER BY %s %s, id ASC
+	LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{name, city, country, filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	cinemas := []*Cinema{}
+
+	for rows.Next() {
+		var cinema Cinema
+
+		err := rows.Scan(
+			&totalRecords,
+			&cinema.ID,
+			&cinema.CreatedAt,
+			&cinema.Name,
+			&cinema.Address,
+			&cinema.City,
+			&cinema.Country,
+			&cinema.Timezone,
+			&cinema.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		cinemas = append(cinemas, &cinema)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return cinemas, metadata, nil
+}
@@ -0,0 +1,261 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// FindDuplicates returns the ids of movies with the same normalised title and
+// year that the viewer may see, which are published ones, ones they created,
+// or any when includeUnpublished is set.
+func (m *MovieModel) FindDuplicates(movie *Movie, viewerID int64, includeUnpublished bool) ([]int64, error) {
+	query := `
+		SELECT id
+		FROM movies
+		WHERE lower(regexp_replace(title, '[^[:alnum:]]+', '', 'g')) = lower(regexp_replace($1, '[^[:alnum:]]+', '', 'g'))
+		AND year = $2
+		AND id <> $3
+		AND (status = 'published' OR $4 OR created_by = $5)
+		ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movie.Title, movie.Year, movie.ID, includeUnpublished, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Merge folds the duplicate movie into the canonical one and deletes it,
+// leaving a redirect so that requests for the old id can be forwarded. It
+// returns the blob keys of the duplicate's subtitles that were dropped because
+// the canonical movie already had the language, which the caller is
+// responsible for removing.
+func (m *MovieModel) Merge(duplicateID, canonicalID int64) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		imdbID         sql.NullString
+		imdbRating     sql.NullFloat64
+		imdbVotes      sql.NullInt64
+		budgetAmount   sql.NullInt64
+		budgetCurrency sql.NullString
+	)
+
+	err = tx.QueryRowContext(ctx, `SELECT id FROM movies WHERE id = $1 FOR UPDATE`, canonicalID).Scan(&canonicalID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	query := `
+		INSERT INTO movie_view_buckets (movie_id, bucket, views, hits)
+		SELECT $1, bucket, views, hits
+		FROM movie_view_buckets
+		WHERE movie_id = $2
+		ON CONFLICT (movie_id, bucket) DO UPDATE
+		SET views = movie_view_buckets.views + EXCLUDED.views, hits = movie_view_buckets.hits + EXCLUDED.hits`
+
+	_, err = tx.ExecContext(ctx, query, canonicalID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		UPDATE subtitles
+		SET movie_id = $1
+		WHERE movie_id = $2
+		AND language NOT IN (SELECT language FROM subtitles WHERE movie_id = $1)`
+
+	_, err = tx.ExecContext(ctx, query, canonicalID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+
+	var blobKeys []string
+
+	query = `SELECT ARRAY(SELECT blob_key FROM subtitles WHERE movie_id = $1)`
+
+	err = tx.QueryRowContext(ctx, query, duplicateID).Scan(pq.Array(&blobKeys))
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		UPDATE box_office
+		SET movie_id = $1
+		WHERE movie_id = $2
+		AND region NOT IN (SELECT region FROM box_office WHERE movie_id = $1)`
+
+	_, err = tx.ExecContext(ctx, query, canonicalID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A nomination both movies share is kept on the canonical movie, and
+	// counts as won if either copy of it was.
+	query = `
+		UPDATE award_nominations c
+		SET won = c.won OR n.won
+		FROM award_nominations n
+		WHERE c.movie_id = $1
+		AND n.movie_id = $2
+		AND c.category_id = n.category_id
+		AND c.nominee = n.nominee
+		AND n.won AND NOT c.won`
+
+	_, err = tx.ExecContext(ctx, query, canonicalID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		UPDATE award_nominations n
+		SET movie_id = $1
+		WHERE n.movie_id = $2
+		AND NOT EXISTS (
+			SELECT 1 FROM award_nominations c
+			WHERE c.movie_id = $1 AND c.category_id = n.category_id AND c.nominee = n.nominee)`
+
+	_, err = tx.ExecContext(ctx, query, canonicalID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		UPDATE videos
+		SET movie_id = $1
+		WHERE movie_id = $2
+		AND url NOT IN (SELECT url FROM videos WHERE movie_id = $1)`
+
+	_, err = tx.ExecContext(ctx, query, canonicalID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		UPDATE movie_tags t
+		SET movie_id = $1
+		WHERE t.movie_id = $2
+		AND NOT EXISTS (
+			SELECT 1 FROM movie_tags c
+			WHERE c.movie_id = $1 AND c.tag_id = t.tag_id AND c.user_id = t.user_id)`
+
+	_, err = tx.ExecContext(ctx, query, canonicalID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE showtimes SET movie_id = $1 WHERE movie_id = $2`, canonicalID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		DELETE FROM movies
+		WHERE id = $1
+		RETURNING imdb_id, imdb_rating, imdb_votes, budget_amount, budget_currency`
+
+	err = tx.QueryRowContext(ctx, query, duplicateID).Scan(&imdbID, &imdbRating, &imdbVotes, &budgetAmount, &budgetCurrency)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	query = `
+		UPDATE movies
+		SET imdb_id = COALESCE(imdb_id, $2),
+			imdb_rating = COALESCE(imdb_rating, $3),
+			imdb_votes = COALESCE(imdb_votes, $4),
+			budget_currency = CASE WHEN budget_amount IS NULL THEN $6 ELSE budget_currency END,
+			budget_amount = COALESCE(budget_amount, $5),
+			version = version + 1
+		WHERE id = $1`
+
+	_, err = tx.ExecContext(ctx, query, canonicalID, imdbID, imdbRating, imdbVotes, budgetAmount, budgetCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, refreshMovieTagsQuery, canonicalID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE movie_redirects SET movie_id = $1 WHERE movie_id = $2`, canonicalID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO movie_redirects (old_id, movie_id) VALUES ($1, $2)`, duplicateID, canonicalID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return blobKeys, nil
+}
+
+func (m *MovieModel) GetRedirect(id int64) (int64, error) {
+	query := `
+		SELECT movie_id
+		FROM movie_redirects
+		WHERE old_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var movieID int64
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return movieID, nil
+}
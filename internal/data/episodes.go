@@ -0,0 +1,234 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+type Episode struct {
+	ID        int64     `json:"id"`
+	SeasonID  int64     `json:"season_id"`
+	Number    int32     `json:"number"`
+	Title     string    `json:"title"`
+	Runtime   Runtime   `json:"runtime,omitempty"`
+	AirDate   *Date     `json:"air_date,omitempty"`
+	Version   int32     `json:"version"`
+	CreatedAt time.Time `json:"-"`
+
+	RuntimeFormat RuntimeFormat `json:"-"`
+}
+
+func (e Episode) MarshalJSON() ([]byte, error) {
+	type episode Episode
+
+	var runtime interface{}
+	if e.Runtime != 0 {
+		runtime = e.Runtime.Format(e.RuntimeFormat)
+	}
+
+	aux := struct {
+		episode
+		Runtime interface{} `json:"runtime,omitempty"`
+	}{
+		episode: episode(e),
+		Runtime: runtime,
+	}
+
+	return json.Marshal(aux)
+}
+
+func ValidateEpisode(v *validator.Validator, episode *Episode) {
+	v.Check(episode.Number >= 1, "number", "must be greater than zero")
+	v.Check(episode.Number <= 10000, "number", "must not be more than 10000")
+
+	v.Check(episode.Title != "", "title", "must be provided")
+	v.Check(len(episode.Title) <= 500, "title", "must not be more than 500 bytes long")
+
+	v.Check(episode.Runtime != 0, "runtime", "must be provided")
+	v.Check(episode.Runtime > 0, "runtime", "must be a positive integer")
+
+	if episode.AirDate != nil {
+		v.Check(episode.AirDate.Year() >= 1888, "air_date", "must be after 1888")
+	}
+}
+
+type EpisodeModel struct {
+	DB *sql.DB
+}
+
+func (m *EpisodeModel) Insert(episode *Episode) error {
+	query := `
+		INSERT INTO episodes (season_id, number, title, runtime, air_date)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, version`
+
+	args := []interface{}{episode.SeasonID, episode.Number, episode.Title, episode.Runtime, episode.airDate()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&episode.ID, &episode.CreatedAt, &episode.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "episodes_season_id_number_key"):
+			return ErrDuplicateNumber
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *EpisodeModel) Get(seasonID int64, number int32) (*Episode, error) {
+	query := `
+		SELECT id, created_at, season_id, number, title, runtime, air_date, version
+		FROM episodes
+		WHERE season_id = $1 AND number = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	episode, err := scanEpisode(m.DB.QueryRowContext(ctx, query, seasonID, number))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return episode, nil
+}
+
+func (m *EpisodeModel) GetAllForSeason(seasonID int64) ([]*Episode, error) {
+	query := `
+		SELECT id, created_at, season_id, number, title, runtime, air_date, version
+		FROM episodes
+		WHERE season_id = $1
+		ORDER BY number`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	episodes := []*Episode{}
+
+	for rows.Next() {
+		episode, err := scanEpisode(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		episodes = append(episodes, episode)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return episodes, nil
+}
+
+func (m *EpisodeModel) Update(episode *Episode) error {
+	query := `
+		UPDATE episodes
+		SET number = $1, title = $2, runtime = $3, air_date = $4, version = version + 1
+		WHERE id = $5 AND version = $6
+		RETURNING version`
+
+	args := []interface{}{
+		episode.Number,
+		episode.Title,
+		episode.Runtime,
+		episode.airDate(),
+		episode.ID,
+		episode.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&episode.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "episodes_season_id_number_key"):
+			return ErrDuplicateNumber
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *EpisodeModel) Delete(id int64) error {
+	query := `DELETE FROM episodes WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (e *Episode) airDate() *time.Time {
+	if e.AirDate == nil {
+		return nil
+	}
+	return &e.AirDate.Time
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEpisode(row rowScanner) (*Episode, error) {
+	var episode Episode
+	var airDate sql.NullTime
+
+	err := row.Scan(
+		&episode.ID,
+		&episode.CreatedAt,
+		&episode.SeasonID,
+		&episode.Number,
+		&episode.Title,
+		&episode.Runtime,
+		&airDate,
+		&episode.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if airDate.Valid {
+		episode.AirDate = &Date{Time: airDate.Time}
+	}
+
+	return &episode, nil
+}
@@ -0,0 +1,73 @@
+package data
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type IMDbMovie struct {
+	IMDbID string
+	Movie  *Movie
+	Rating float64
+	Votes  int64
+}
+
+func (m *MovieModel) UpsertIMDb(movies []*IMDbMovie) (int64, error) {
+	if len(movies) == 0 {
+		return 0, nil
+	}
+
+	query := `
+		INSERT INTO movies (imdb_id, title, year, runtime, genres, imdb_rating, imdb_votes)
+		SELECT i.imdb_id, i.title, i.year, i.runtime, string_to_array(i.genres, ','), NULLIF(i.rating, 0), NULLIF(i.votes, 0)
+		FROM unnest($1::text[], $2::text[], $3::integer[], $4::integer[], $5::text[], $6::real[], $7::integer[])
+			AS i(imdb_id, title, year, runtime, genres, rating, votes)
+		ON CONFLICT (imdb_id) DO UPDATE
+		SET title = EXCLUDED.title, year = EXCLUDED.year, runtime = EXCLUDED.runtime, genres = EXCLUDED.genres,
+			imdb_rating = EXCLUDED.imdb_rating, imdb_votes = EXCLUDED.imdb_votes, version = movies.version + 1
+		WHERE (movies.title, movies.year, movies.runtime, movies.genres, movies.imdb_rating, movies.imdb_votes)
+			IS DISTINCT FROM (EXCLUDED.title, EXCLUDED.year, EXCLUDED.runtime, EXCLUDED.genres, EXCLUDED.imdb_rating, EXCLUDED.imdb_votes)`
+
+	var (
+		ids      = make([]string, len(movies))
+		titles   = make([]string, len(movies))
+		years    = make([]int64, len(movies))
+		runtimes = make([]int64, len(movies))
+		genres   = make([]string, len(movies))
+		ratings  = make([]float64, len(movies))
+		votes    = make([]int64, len(movies))
+	)
+
+	for i, movie := range movies {
+		ids[i] = movie.IMDbID
+		titles[i] = movie.Movie.Title
+		years[i] = int64(movie.Movie.Year)
+		runtimes[i] = int64(movie.Movie.Runtime)
+		genres[i] = strings.Join(movie.Movie.Genres, ",")
+		ratings[i] = movie.Rating
+		votes[i] = movie.Votes
+	}
+
+	args := []interface{}{
+		pq.Array(ids),
+		pq.Array(titles),
+		pq.Array(years),
+		pq.Array(runtimes),
+		pq.Array(genres),
+		pq.Array(ratings),
+		pq.Array(votes),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,197 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// LoginFailure counts consecutive failed sign in attempts for a key, which is
+// either an email address or a client IP address.
+type LoginFailure struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+
+	// previousFailureAt is LastFailureAt before the claim, which Release
+	// puts back.
+	previousFailureAt time.Time
+}
+
+func LoginEmailKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+func LoginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// LoginLimit is the policy for a kind of key. Each failure doubles the wait
+// before the next attempt, starting at Backoff and capped at Lockout, and the
+// key is locked for the Lockout once it reaches MaxFailures. Failures older
+// than the Lockout are forgotten.
+type LoginLimit struct {
+	MaxFailures int
+	Lockout     time.Duration
+	Backoff     time.Duration
+}
+
+// Wait returns how long after now the next attempt for the key is allowed.
+func (l LoginLimit) Wait(failure *LoginFailure, now time.Time) time.Duration {
+	until := time.Time{}
+
+	if failure.LockedUntil != nil {
+		until = *failure.LockedUntil
+	}
+
+	if l.Backoff > 0 && failure.Failures > 0 {
+		delay := l.Lockout
+		if failure.Failures < 32 {
+			delay = l.Backoff << (failure.Failures - 1)
+		}
+		if delay <= 0 || delay > l.Lockout {
+			delay = l.Lockout
+		}
+
+		if backoff := failure.LastFailureAt.Add(delay); backoff.After(until) {
+			until = backoff
+		}
+	}
+
+	if wait := until.Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+type LoginFailureModel struct {
+	DB *sql.DB
+}
+
+// Claim counts an attempt for the key as a failure before the credentials are
+// checked, unless the key is locked or backing off, in which case it returns
+// how long to wait instead. The row is locked while this is decided, so that
+// concurrent attempts cannot all pass the limit. A successful attempt gives
+// its claim back with Release.
+func (m LoginFailureModel) Claim(key string, limit LoginLimit) (*LoginFailure, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO login_failures (key, failures, last_failure_at)
+		VALUES ($1, 0, NOW())
+		ON CONFLICT (key) DO NOTHING`
+
+	_, err = tx.ExecContext(ctx, query, key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query = `
+		SELECT key, failures, last_failure_at, locked_until, NOW()
+		FROM login_failures
+		WHERE key = $1
+		FOR UPDATE`
+
+	var (
+		failure LoginFailure
+		now     time.Time
+	)
+
+	err = tx.QueryRowContext(ctx, query, key).Scan(&failure.Key, &failure.Failures, &failure.LastFailureAt, &failure.LockedUntil, &now)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if wait := limit.Wait(&failure, now); wait > 0 {
+		return nil, wait, nil
+	}
+
+	failure.previousFailureAt = failure.LastFailureAt
+
+	query = `
+		UPDATE login_failures
+		SET failures = CASE
+				WHEN last_failure_at < NOW() - make_interval(secs => $2) THEN 1
+				ELSE failures + 1
+			END,
+			last_failure_at = NOW()
+		WHERE key = $1
+		RETURNING failures, last_failure_at`
+
+	err = tx.QueryRowContext(ctx, query, key, limit.Lockout.Seconds()).Scan(&failure.Failures, &failure.LastFailureAt)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if failure.Failures >= limit.MaxFailures {
+		query = `
+			UPDATE login_failures
+			SET locked_until = NOW() + make_interval(secs => $2)
+			WHERE key = $1
+			RETURNING locked_until`
+
+		err = tx.QueryRowContext(ctx, query, key, limit.Lockout.Seconds()).Scan(&failure.LockedUntil)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return &failure, 0, tx.Commit()
+}
+
+// Release gives back an attempt returned by Claim, lifting the lock if the
+// claim was what set it. The time of the last failure goes back to what it
+// was before the claim, unless another attempt has failed since, so that a
+// successful attempt does not start a new backoff.
+func (m LoginFailureModel) Release(claim *LoginFailure, limit LoginLimit) error {
+	query := `
+		UPDATE login_failures
+		SET failures = GREATEST(failures - 1, 0),
+			last_failure_at = CASE WHEN last_failure_at = $3 THEN $4 ELSE last_failure_at END,
+			locked_until = CASE WHEN failures - 1 >= $2 THEN locked_until END
+		WHERE key = $1`
+
+	args := []interface{}{claim.Key, limit.MaxFailures, claim.LastFailureAt, claim.previousFailureAt}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (m LoginFailureModel) Clear(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM login_failures WHERE key = $1`, key)
+	return err
+}
+
+// Prune removes keys whose last failure is older than the cutoff and that are
+// no longer locked.
+func (m LoginFailureModel) Prune(cutoff time.Time) (int64, error) {
+	query := `
+		DELETE FROM login_failures
+		WHERE last_failure_at < $1
+		AND (locked_until IS NULL OR locked_until < NOW())`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
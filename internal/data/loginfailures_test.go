@@ -0,0 +1,40 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginLimitWait(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	lockedUntil := now.Add(10 * time.Minute)
+	expiredLock := now.Add(-time.Minute)
+
+	email := LoginLimit{MaxFailures: 5, Lockout: 15 * time.Minute, Backoff: time.Second}
+	ip := LoginLimit{MaxFailures: 50, Lockout: 15 * time.Minute}
+
+	tests := []struct {
+		name    string
+		limit   LoginLimit
+		failure LoginFailure
+		want    time.Duration
+	}{
+		{"no failures", email, LoginFailure{LastFailureAt: now}, 0},
+		{"first failure", email, LoginFailure{Failures: 1, LastFailureAt: now}, time.Second},
+		{"third failure", email, LoginFailure{Failures: 3, LastFailureAt: now}, 4 * time.Second},
+		{"backoff partly elapsed", email, LoginFailure{Failures: 3, LastFailureAt: now.Add(-3 * time.Second)}, time.Second},
+		{"backoff elapsed", email, LoginFailure{Failures: 3, LastFailureAt: now.Add(-5 * time.Second)}, 0},
+		{"backoff capped at lockout", email, LoginFailure{Failures: 20, LastFailureAt: now}, 15 * time.Minute},
+		{"backoff does not overflow", email, LoginFailure{Failures: 64, LastFailureAt: now}, 15 * time.Minute},
+		{"locked", email, LoginFailure{Failures: 5, LastFailureAt: now.Add(-time.Hour), LockedUntil: &lockedUntil}, 10 * time.Minute},
+		{"lock expired", email, LoginFailure{Failures: 5, LastFailureAt: now.Add(-time.Hour), LockedUntil: &expiredLock}, 0},
+		{"ip without backoff", ip, LoginFailure{Failures: 49, LastFailureAt: now}, 0},
+		{"ip locked", ip, LoginFailure{Failures: 50, LastFailureAt: now, LockedUntil: &lockedUntil}, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := tt.limit.Wait(&tt.failure, now); got != tt.want {
+			t.Errorf("%s: Wait = %s; want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package data
 import (
 	"database/sql"
 	"errors"
+	"strings"
 )
 
 var (
@@ -11,17 +12,61 @@ var (
 )
 
 type Models struct {
-	Movies      MovieModel
-	Permissions PermissionModel
-	Tokens      TokenModel
-	Users       UserModel
+	APIKeys       APIKeyModel
+	Awards        AwardModel
+	BoxOffice     BoxOfficeModel
+	Cinemas       CinemaModel
+	Episodes      EpisodeModel
+	ExchangeRates ExchangeRateModel
+	LoginFailures LoginFailureModel
+	MFA           MFAModel
+	Movies        MovieModel
+	OAuthClients  OAuthClientModel
+	Permissions   PermissionModel
+	Reservations  ReservationModel
+	Screens       ScreenModel
+	Search        SearchModel
+	Seasons       SeasonModel
+	Series        SeriesModel
+	Showtimes     ShowtimeModel
+	SigningKeys   SigningKeyModel
+	Subtitles     SubtitleModel
+	Tags          TagModel
+	Tokens        TokenModel
+	Users         UserModel
+	Videos        VideoModel
+	Views         ViewModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Movies:      MovieModel{DB: db},
-		Permissions: PermissionModel{DB: db},
-		Tokens:      TokenModel{DB: db},
-		Users:       UserModel{DB: db},
+		APIKeys:       APIKeyModel{DB: db},
+		Awards:        AwardModel{DB: db},
+		BoxOffice:     BoxOfficeModel{DB: db},
+		Cinemas:       CinemaModel{DB: db},
+		Episodes:      EpisodeModel{DB: db},
+		ExchangeRates: ExchangeRateModel{DB: db},
+		LoginFailures: LoginFailureModel{DB: db},
+		MFA:           MFAModel{DB: db},
+		Movies:        MovieModel{DB: db},
+		OAuthClients:  OAuthClientModel{DB: db},
+		Permissions:   PermissionModel{DB: db},
+		Reservations:  ReservationModel{DB: db},
+		Screens:       ScreenModel{DB: db},
+		Search:        SearchModel{DB: db},
+		Seasons:       SeasonModel{DB: db},
+		Series:        SeriesModel{DB: db},
+		Showtimes:     ShowtimeModel{DB: db},
+		SigningKeys:   SigningKeyModel{DB: db},
+		Subtitles:     SubtitleModel{DB: db},
+		Tags:          TagModel{DB: db},
+		Tokens:        TokenModel{DB: db},
+		Users:         UserModel{DB: db},
+		Videos:        VideoModel{DB: db},
+		Views:         ViewModel{DB: db},
 	}
 }
+
+func isUniqueViolation(err error, constraint string) bool {
+	return strings.Contains(err.Error(), "SQLSTATE 23505") && strings.Contains(err.Error(), `"`+constraint+`"`)
+}
@@ -0,0 +1,171 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+var (
+	ErrInvalidAmount   = errors.New("invalid amount")
+	ErrUnknownCurrency = errors.New("unknown currency")
+	ErrMissingRate     = errors.New("missing exchange rate")
+
+	amountRX = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+)
+
+const ReportingCurrency = "USD"
+
+// currencyExponents holds the ISO 4217 minor unit exponent of the currencies
+// we accept, i.e. the number of decimal places between the major and the minor
+// unit.
+var currencyExponents = map[string]int{
+	"AED": 2, "ARS": 2, "AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2,
+	"CLP": 0, "CNY": 2, "COP": 2, "CZK": 2, "DKK": 2, "EGP": 2, "EUR": 2,
+	"GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "ISK": 0,
+	"JOD": 3, "JPY": 0, "KRW": 0, "KWD": 3, "MXN": 2, "MYR": 2, "NGN": 2,
+	"NOK": 2, "NZD": 2, "OMR": 3, "PEN": 2, "PHP": 2, "PLN": 2, "PYG": 0,
+	"RUB": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2, "TND": 3, "TRY": 2,
+	"TWD": 2, "UGX": 0, "USD": 2, "VND": 0, "XAF": 0, "XOF": 0, "ZAR": 2,
+}
+
+func CurrencyExponent(currency string) (int, bool) {
+	exponent, ok := currencyExponents[currency]
+	return exponent, ok
+}
+
+func ValidateCurrency(v *validator.Validator, key, currency string) {
+	_, ok := CurrencyExponent(currency)
+	v.Check(ok, key, "must be a supported ISO 4217 currency code such as USD or JPY")
+}
+
+// Money is an exact amount in the minor unit of its currency, e.g. cents for
+// USD and yen for JPY. It is written to and read from JSON as a decimal string
+// in the major unit so that no precision is lost on either side.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func (m Money) String() string {
+	exponent, ok := CurrencyExponent(m.Currency)
+	if !ok || exponent == 0 {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+
+	digits := fmt.Sprintf("%0*d", exponent+1, m.Amount)
+	return digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount      string `json:"amount"`
+		AmountMinor int64  `json:"amount_minor"`
+		Currency    string `json:"currency"`
+	}{m.String(), m.Amount, m.Currency})
+}
+
+func (m *Money) UnmarshalJSON(jsonValue []byte) error {
+	var input struct {
+		Amount   json.Number `json:"amount"`
+		Currency string      `json:"currency"`
+	}
+
+	err := json.Unmarshal(jsonValue, &input)
+	if err != nil {
+		return ErrInvalidAmount
+	}
+
+	m.Currency = strings.ToUpper(input.Currency)
+
+	m.Amount, err = ParseAmount(input.Amount.String(), m.Currency)
+	return err
+}
+
+// ParseAmount converts a non-negative decimal amount in the major unit of the
+// currency into minor units, refusing amounts with more decimal places than the
+// currency has.
+func ParseAmount(amount, currency string) (int64, error) {
+	exponent, ok := CurrencyExponent(currency)
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownCurrency, currency)
+	}
+
+	if !amountRX.MatchString(amount) {
+		return 0, fmt.Errorf("%w: must be a non-negative decimal number", ErrInvalidAmount)
+	}
+
+	whole, fraction, _ := strings.Cut(amount, ".")
+	if len(fraction) > exponent {
+		return 0, fmt.Errorf("%w: %s allows at most %d decimal places", ErrInvalidAmount, currency, exponent)
+	}
+
+	minor, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", exponent-len(fraction)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: too large", ErrInvalidAmount)
+	}
+
+	return minor, nil
+}
+
+// Rates maps a currency to the value of one of its major units in the
+// reporting currency.
+type Rates map[string]*big.Rat
+
+// Convert expresses m in the target currency, rounding half away from zero to
+// the target's minor unit.
+func (r Rates) Convert(m Money, currency string) (Money, error) {
+	if m.Currency == currency {
+		return m, nil
+	}
+
+	from, err := r.rate(m.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	to, err := r.rate(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	fromExponent, _ := CurrencyExponent(m.Currency)
+	toExponent, _ := CurrencyExponent(currency)
+
+	amount := new(big.Rat).SetInt64(m.Amount)
+	amount.Mul(amount, from)
+	amount.Quo(amount, to)
+	amount.Mul(amount, new(big.Rat).SetFrac(pow10(toExponent), pow10(fromExponent)))
+
+	// Rounds half away from zero; amounts are never negative.
+	num, den := amount.Num(), amount.Denom()
+	rounded := new(big.Int).Quo(new(big.Int).Add(new(big.Int).Mul(num, big.NewInt(2)), den), new(big.Int).Mul(den, big.NewInt(2)))
+
+	if !rounded.IsInt64() {
+		return Money{}, fmt.Errorf("%w: too large", ErrInvalidAmount)
+	}
+
+	return Money{Amount: rounded.Int64(), Currency: currency}, nil
+}
+
+func (r Rates) rate(currency string) (*big.Rat, error) {
+	if currency == ReportingCurrency {
+		return big.NewRat(1, 1), nil
+	}
+
+	rate, ok := r[currency]
+	if !ok {
+		return nil, fmt.Errorf("%w for %s", ErrMissingRate, currency)
+	}
+	return rate, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
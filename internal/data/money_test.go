@@ -0,0 +1,150 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     int64
+	}{
+		{"12.34", "USD", 1234},
+		{"12.3", "USD", 1230},
+		{"12", "USD", 1200},
+		{"0.01", "USD", 1},
+		{"1500", "JPY", 1500},
+		{"1.234", "KWD", 1234},
+		{"92233720368547758.07", "USD", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.amount, tt.currency)
+		if err != nil {
+			t.Errorf("ParseAmount(%q, %q): unexpected error: %v", tt.amount, tt.currency, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("ParseAmount(%q, %q) = %d; want %d", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestParseAmountInvalid(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     error
+	}{
+		{"12.345", "USD", ErrInvalidAmount},
+		{"1.5", "JPY", ErrInvalidAmount},
+		{"-1", "USD", ErrInvalidAmount},
+		{"1e3", "USD", ErrInvalidAmount},
+		{".5", "USD", ErrInvalidAmount},
+		{"", "USD", ErrInvalidAmount},
+		{"92233720368547758.08", "USD", ErrInvalidAmount},
+		{"1", "XXX", ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseAmount(tt.amount, tt.currency)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("ParseAmount(%q, %q) error = %v; want %v", tt.amount, tt.currency, err, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{1234, "USD"}, "12.34"},
+		{Money{5, "USD"}, "0.05"},
+		{Money{0, "USD"}, "0.00"},
+		{Money{1500, "JPY"}, "1500"},
+		{Money{1234, "KWD"}, "1.234"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q; want %q", tt.money, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var m Money
+
+	err := json.Unmarshal([]byte(`{"amount": 12.30, "currency": "eur"}`), &m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if m != (Money{1230, "EUR"}) {
+		t.Fatalf("Unmarshal = %+v; want {1230 EUR}", m)
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{"amount":"12.30","amount_minor":1230,"currency":"EUR"}`
+	if string(b) != want {
+		t.Errorf("Marshal = %s; want %s", b, want)
+	}
+
+	err = json.Unmarshal([]byte(`{"amount": 12.345, "currency": "EUR"}`), &m)
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("Unmarshal of too many decimal places error = %v; want ErrInvalidAmount", err)
+	}
+}
+
+func TestRatesConvert(t *testing.T) {
+	rates := Rates{
+		"EUR": big.NewRat(11, 10),
+		"JPY": big.NewRat(67, 10000),
+		"KWD": big.NewRat(325, 100),
+	}
+
+	tests := []struct {
+		money    Money
+		currency string
+		want     Money
+	}{
+		{Money{1234, "USD"}, "USD", Money{1234, "USD"}},
+		{Money{100, "EUR"}, "USD", Money{110, "USD"}},
+		{Money{1000, "JPY"}, "USD", Money{670, "USD"}},
+		{Money{110, "USD"}, "EUR", Money{100, "EUR"}},
+		{Money{1000, "KWD"}, "USD", Money{325, "USD"}},
+		{Money{100, "EUR"}, "JPY", Money{164, "JPY"}},
+		// 1 JPY is 0.67 cents, which rounds up to 1 cent.
+		{Money{1, "JPY"}, "USD", Money{1, "USD"}},
+		// 0.5 cents rounds half away from zero.
+		{Money{1, "KWD"}, "EUR", Money{0, "EUR"}},
+		{Money{2, "KWD"}, "EUR", Money{1, "EUR"}},
+	}
+
+	for _, tt := range tests {
+		got, err := rates.Convert(tt.money, tt.currency)
+		if err != nil {
+			t.Errorf("Convert(%+v, %q): unexpected error: %v", tt.money, tt.currency, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("Convert(%+v, %q) = %+v; want %+v", tt.money, tt.currency, got, tt.want)
+		}
+	}
+
+	_, err := rates.Convert(Money{100, "GBP"}, "USD")
+	if !errors.Is(err, ErrMissingRate) {
+		t.Errorf("Convert without a rate error = %v; want ErrMissingRate", err)
+	}
+}
@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -12,13 +13,39 @@ import (
 )
 
 type Movie struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	Year      int32     `json:"year,omitempty"`
-	Runtime   Runtime   `json:"runtime,omitempty"`
-	Genres    []string  `json:"genres,omitempty"`
-	Version   int32     `json:"version"`
-	CreatedAt time.Time `json:"-"`
+	ID        int64      `json:"id"`
+	Title     string     `json:"title"`
+	Year      int32      `json:"year,omitempty"`
+	Runtime   Runtime    `json:"runtime,omitempty"`
+	Genres    []string   `json:"genres,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	Version   int32      `json:"version"`
+	CreatedBy int64      `json:"created_by,omitempty"`
+	UpdatedBy int64      `json:"updated_by,omitempty"`
+	CreatedAt time.Time  `json:"-"`
+
+	RuntimeFormat RuntimeFormat `json:"-"`
+}
+
+func (m Movie) MarshalJSON() ([]byte, error) {
+	type movie Movie
+
+	var runtime interface{}
+	if m.Runtime != 0 {
+		runtime = m.Runtime.Format(m.RuntimeFormat)
+	}
+
+	aux := struct {
+		movie
+		Runtime interface{} `json:"runtime,omitempty"`
+	}{
+		movie:   movie(m),
+		Runtime: runtime,
+	}
+
+	return json.Marshal(aux)
 }
 
 func ValidateMovie(v *validator.Validator, movie *Movie) {
@@ -39,17 +66,30 @@ func ValidateMovie(v *validator.Validator, movie *Movie) {
 	v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
 }
 
+type MovieQuery struct {
+	Title     string
+	Genres    []string
+	Tags      []string
+	CreatedBy int64
+	Status    string
+	Quote     string
+	WonAward  string
+
+	ViewerID           int64
+	IncludeUnpublished bool
+}
+
 type MovieModel struct {
 	DB *sql.DB
 }
 
 func (m *MovieModel) Insert(movie *Movie) error {
 	query := `
-		INSERT INTO movies (title,year,runtime,genres)
-		VALUES ($1,$2,$3,$4)
+		INSERT INTO movies (title,year,runtime,genres,status,created_by)
+		VALUES ($1,$2,$3,$4,$5,$6)
 		RETURNING id,created_at,version`
 
-	args := []interface{}{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres)}
+	args := []interface{}{movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres), movie.Status, movie.CreatedBy}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -62,7 +102,7 @@ func (m *MovieModel) Get(id int64) (*Movie, error) {
 	}
 
 	query := `
-		SELECT id, created_at, title, year, runtime, genres, version
+		SELECT id, created_at, title, year, runtime, genres, tags, status, publish_at, version, COALESCE(created_by, 0), COALESCE(updated_by, 0)
 		FROM movies
 		WHERE id = $1`
 
@@ -78,7 +118,12 @@ func (m *MovieModel) Get(id int64) (*Movie, error) {
 		&movie.Year,
 		&movie.Runtime,
 		pq.Array(&movie.Genres),
+		pq.Array(&movie.Tags),
+		&movie.Status,
+		&movie.PublishAt,
 		&movie.Version,
+		&movie.CreatedBy,
+		&movie.UpdatedBy,
 	)
 
 	if err != nil {
@@ -96,8 +141,8 @@ func (m *MovieModel) Get(id int64) (*Movie, error) {
 func (m *MovieModel) Update(movie *Movie) error {
 	query := `
 		UPDATE movies
-		SET title = $1, year = $2, runtime = $3, genres = $4, version = version + 1 
-		WHERE id = $5 AND version = $6
+		SET title = $1, year = $2, runtime = $3, genres = $4, updated_by = $5, version = version + 1
+		WHERE id = $6 AND version = $7
 		RETURNING version`
 
 	args := []interface{}{
@@ -105,6 +150,7 @@ func (m *MovieModel) Update(movie *Movie) error {
 		movie.Year,
 		movie.Runtime,
 		pq.Array(movie.Genres),
+		movie.UpdatedBy,
 		movie.ID,
 		movie.Version,
 	}
@@ -125,48 +171,88 @@ func (m *MovieModel) Update(movie *Movie) error {
 	return nil
 }
 
-func (m *MovieModel) Delete(id int64) error {
+// Delete removes the movie and returns the blob keys of the subtitles deleted
+// along with it, which the caller is responsible for removing.
+func (m *MovieModel) Delete(id int64) ([]string, error) {
 	if id < 1 {
-		return ErrRecordNotFound
+		return nil, ErrRecordNotFound
 	}
 
-	query := `DELETE FROM movies WHERE id = $1`
+	// Both parts of the statement see the subtitles as they were before the
+	// cascade removed them.
+	query := `
+		WITH deleted AS (
+			DELETE FROM movies WHERE id = $1 RETURNING id
+		)
+		SELECT ARRAY(SELECT blob_key FROM subtitles WHERE movie_id = $1)
+		FROM deleted`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
-	if err != nil {
-		return err
-	}
+	var blobKeys []string
 
-	rowsAffected, err := result.RowsAffected()
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(pq.Array(&blobKeys))
 	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
 	}
 
-	return nil
+	return blobKeys, nil
 }
 
-func (m *MovieModel) GetAll(title string, genres []string, filters Filters) ([]*Movie, Metadata, error) {
+func (m *MovieModel) GetAll(q MovieQuery, filters Filters) ([]*Movie, Metadata, error) {
+	sortColumn := filters.sortColumn()
+
+	// The gross view is only consulted, one movie at a time, when sorting by
+	// gross. Movies without box office figures have no row and count as zero.
+	if sortColumn == "gross_usd" {
+		sortColumn = `COALESCE((SELECT gross_usd FROM movie_gross_usd WHERE movie_gross_usd.movie_id = movies.id), 0)`
+	}
+
 	query := fmt.Sprintf(`
-	SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, version
+	SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, tags, status, publish_at, version, COALESCE(created_by, 0), COALESCE(updated_by, 0)
 	FROM movies
 	WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
 	AND (genres @> $2 OR $2 = '{}')
+	AND (tags @> $3 OR $3 = '{}')
+	AND (created_by = $4 OR $4 = 0)
+	AND (status = $5 OR $5 = '')
+	AND (status = 'published' OR $6 OR created_by = $7)
+	AND ($8 = '' OR EXISTS (
+		SELECT 1 FROM subtitles
+		WHERE subtitles.movie_id = movies.id
+		AND to_tsvector('simple', subtitles.text) @@ phraseto_tsquery('simple', $8)))
+	AND ($9 = '' OR EXISTS (
+		SELECT 1 FROM award_nominations
+		INNER JOIN award_categories ON award_categories.id = award_nominations.category_id
+		INNER JOIN award_events ON award_events.id = award_categories.event_id
+		WHERE award_nominations.movie_id = movies.id
+		AND award_nominations.won
+		AND lower(award_events.name) = lower($9)))
 	ORDER BY %s %s, id ASC
-	LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+	LIMIT $10 OFFSET $11`, sortColumn, filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	time.Sleep(time.Minute)
-
-	args := []interface{}{title, pq.Array(genres), filters.limit(), filters.offset()}
+	args := []interface{}{
+		q.Title,
+		pq.Array(q.Genres),
+		pq.Array(q.Tags),
+		q.CreatedBy,
+		q.Status,
+		q.IncludeUnpublished,
+		q.ViewerID,
+		q.Quote,
+		q.WonAward,
+		filters.limit(),
+		filters.offset(),
+	}
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -189,7 +275,12 @@ func (m *MovieModel) GetAll(title string, genres []string, filters Filters) ([]*
 			&movie.Year,
 			&movie.Runtime,
 			pq.Array(&movie.Genres),
+			pq.Array(&movie.Tags),
+			&movie.Status,
+			&movie.PublishAt,
 			&movie.Version,
+			&movie.CreatedBy,
+			&movie.UpdatedBy,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
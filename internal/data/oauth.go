@@ -0,0 +1,134 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	oauthClientIDPrefix     = "chm_client_"
+	oauthClientSecretPrefix = "chm_secret_"
+)
+
+// OAuthClient is a machine identity that acts as its service account user,
+// limited to its own permissions.
+type OAuthClient struct {
+	ID          string      `json:"client_id"`
+	Secret      string      `json:"client_secret,omitempty"`
+	SecretHash  []byte      `json:"-"`
+	Name        string      `json:"name"`
+	UserID      int64       `json:"user_id"`
+	Permissions Permissions `json:"permissions"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+func randomCredential(prefix string, size int) (string, error) {
+	randomBytes := make([]byte, size)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+	return prefix + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)), nil
+}
+
+// SecretMatches compares the secret in constant time.
+func (c *OAuthClient) SecretMatches(secret string) bool {
+	return subtle.ConstantTimeCompare(HashToken(secret), c.SecretHash) == 1
+}
+
+type OAuthClientModel struct {
+	DB *sql.DB
+}
+
+// Insert generates the client's id and secret. The secret is only available
+// on the returned client; just its hash is stored.
+func (m OAuthClientModel) Insert(client *OAuthClient) error {
+	var err error
+
+	client.ID, err = randomCredential(oauthClientIDPrefix, 10)
+	if err != nil {
+		return err
+	}
+
+	client.Secret, err = randomCredential(oauthClientSecretPrefix, 20)
+	if err != nil {
+		return err
+	}
+	client.SecretHash = HashToken(client.Secret)
+
+	query := `
+		INSERT INTO oauth_clients (id, secret_hash, name, user_id, permissions)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at`
+
+	args := []interface{}{client.ID, client.SecretHash, client.Name, client.UserID, pq.Array([]string(client.Permissions))}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&client.CreatedAt)
+}
+
+func (m OAuthClientModel) Get(id string) (*OAuthClient, error) {
+	query := `
+		SELECT id, secret_hash, name, user_id, permissions, created_at
+		FROM oauth_clients
+		WHERE id = $1`
+
+	var client OAuthClient
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&client.ID,
+		&client.SecretHash,
+		&client.Name,
+		&client.UserID,
+		pq.Array((*[]string)(&client.Permissions)),
+		&client.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &client, nil
+}
+
+// Delete removes the client; the tokens issued to it are revoked along with it
+// through the tokens.client_id foreign key.
+func (m OAuthClientModel) Delete(id string) error {
+	query := `DELETE FROM oauth_clients WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrTokenReused is returned when a refresh token that was already rotated is
+// presented again. The whole token family has been revoked by then.
+var ErrTokenReused = errors.New("refresh token reused")
+
+// NewFamily issues an access token and a refresh token that belong to a new
+// token family. Every token later rotated from the refresh token joins the
+// same family, so that all of them can be revoked together. With a zero
+// accessTTL only the refresh token is issued, for callers that sign their own
+// access tokens.
+func (m *TokenModel) NewFamily(userID int64, accessTTL, refreshTTL time.Duration, ip, userAgent string) (*Token, *Token, error) {
+	family := make([]byte, 16)
+	_, err := rand.Read(family)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	access, refresh, err := insertTokenPair(ctx, tx, userID, family, accessTTL, refreshTTL, ip, userAgent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return access, refresh, tx.Commit()
+}
+
+// Rotate exchanges a refresh token for a new access and refresh token pair.
+// Each refresh token can be used once: presenting it a second time means it
+// has leaked, so the whole family is revoked and ErrTokenReused returned.
+func (m *TokenModel) Rotate(refreshPlaintext string, accessTTL, refreshTTL time.Duration, ip, userAgent string) (*Token, *Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+	    SELECT user_id, family, used_at
+	    FROM tokens
+	    WHERE hash = $1 AND scope = $2 AND expiry > NOW()
+	    FOR UPDATE`
+
+	var (
+		userID int64
+		family []byte
+		usedAt *time.Time
+	)
+
+	err = tx.QueryRowContext(ctx, query, HashToken(refreshPlaintext), ScopeRefresh).Scan(&userID, &family, &usedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+
+	if usedAt != nil {
+		_, err = tx.ExecContext(ctx, `DELETE FROM tokens WHERE family = $1`, family)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return nil, nil, ErrTokenReused
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE tokens SET used_at = NOW() WHERE hash = $1`, HashToken(refreshPlaintext))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The access token issued alongside the used refresh token is replaced
+	// too, so that a family never holds more than one live access token.
+	_, err = tx.ExecContext(ctx, `DELETE FROM tokens WHERE family = $1 AND scope = $2`, family, ScopeAuthentication)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	access, refresh, err := insertTokenPair(ctx, tx, userID, family, accessTTL, refreshTTL, ip, userAgent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return access, refresh, tx.Commit()
+}
+
+func insertTokenPair(ctx context.Context, tx *sql.Tx, userID int64, family []byte, accessTTL, refreshTTL time.Duration, ip, userAgent string) (*Token, *Token, error) {
+	refresh, err := generateToken(userID, refreshTTL, ScopeRefresh)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokens := []*Token{refresh}
+
+	var access *Token
+	if accessTTL > 0 {
+		access, err = generateToken(userID, accessTTL, ScopeAuthentication)
+		if err != nil {
+			return nil, nil, err
+		}
+		tokens = append(tokens, access)
+	}
+
+	for _, token := range tokens {
+		token.IP = ip
+		token.UserAgent = userAgent
+		token.Family = family
+
+		_, err = tx.ExecContext(ctx, insertTokenQuery, token.insertArgs()...)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return access, refresh, nil
+}
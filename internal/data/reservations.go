@@ -0,0 +1,333 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/validator"
+	"github.com/lib/pq"
+)
+
+const (
+	ReservationHeld      = "held"
+	ReservationConfirmed = "confirmed"
+	ReservationCancelled = "cancelled"
+	ReservationExpired   = "expired"
+)
+
+// MaxSeatsPerUser caps the seats one user may hold or book for a showtime,
+// across all of their reservations for it.
+const MaxSeatsPerUser = 10
+
+var (
+	ErrHoldExpired             = errors.New("hold expired")
+	ErrInvalidReservationState = errors.New("invalid reservation state")
+	ErrSeatLimit               = errors.New("seat limit reached")
+	ErrShowtimeStarted         = errors.New("showtime already started")
+)
+
+// SeatsTakenError reports the requested seats that are already held or
+// booked by someone else.
+type SeatsTakenError struct {
+	Seats []string
+}
+
+func (e *SeatsTakenError) Error() string {
+	return "seats already taken"
+}
+
+type Reservation struct {
+	ID         int64      `json:"id"`
+	ShowtimeID int64      `json:"showtime_id"`
+	UserID     int64      `json:"-"`
+	Seats      []string   `json:"seats"`
+	Status     string     `json:"status"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Version    int32      `json:"version"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func ValidateReservation(v *validator.Validator, reservation *Reservation, seatMap SeatMap) {
+	v.Check(len(reservation.Seats) >= 1, "seats", "must contain at least 1 seat")
+	v.Check(len(reservation.Seats) <= MaxSeatsPerUser, "seats", fmt.Sprintf("must not contain more than %d seats", MaxSeatsPerUser))
+	v.Check(validator.Unique(reservation.Seats), "seats", "must not contain duplicate values")
+
+	for _, seat := range reservation.Seats {
+		if !seatMap.Has(seat) {
+			v.AddError("seats", "seat "+seat+" does not exist on this screen")
+			break
+		}
+	}
+}
+
+type ReservationModel struct {
+	DB *sql.DB
+}
+
+// Hold reserves the seats for ttl. The showtime row is locked so that holds
+// for the same showtime are serialized, and the (showtime_id, seat) primary
+// key of reservation_seats guarantees a seat is never held twice. Expired
+// holds on the showtime are released first so their seats can be taken. It
+// fails with ErrSeatLimit when the user would end up with more than
+// MaxSeatsPerUser seats for the showtime.
+func (m *ReservationModel) Hold(reservation *Reservation, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT id FROM showtimes WHERE id = $1 FOR UPDATE`, reservation.ShowtimeID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, releaseExpiredQuery, reservation.ShowtimeID)
+	if err != nil {
+		return err
+	}
+
+	// Only live holds and bookings still have rows in reservation_seats.
+	query := `
+		SELECT count(*)
+		FROM reservation_seats rs
+		INNER JOIN reservations r ON r.id = rs.reservation_id
+		WHERE rs.showtime_id = $1 AND r.user_id = $2`
+
+	var held int
+
+	err = tx.QueryRowContext(ctx, query, reservation.ShowtimeID, reservation.UserID).Scan(&held)
+	if err != nil {
+		return err
+	}
+
+	if held+len(reservation.Seats) > MaxSeatsPerUser {
+		return ErrSeatLimit
+	}
+
+	query = `
+		SELECT seat
+		FROM reservation_seats
+		WHERE showtime_id = $1 AND seat = ANY($2)
+		ORDER BY seat`
+
+	rows, err := tx.QueryContext(ctx, query, reservation.ShowtimeID, pq.Array(reservation.Seats))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	taken := []string{}
+	for rows.Next() {
+		var seat string
+		err := rows.Scan(&seat)
+		if err != nil {
+			return err
+		}
+		taken = append(taken, seat)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if len(taken) > 0 {
+		return &SeatsTakenError{Seats: taken}
+	}
+
+	query = `
+		INSERT INTO reservations (showtime_id, user_id, status, expires_at)
+		VALUES ($1, $2, $3, NOW() + $4 * interval '1 second')
+		RETURNING id, created_at, status, expires_at, version`
+
+	args := []interface{}{reservation.ShowtimeID, reservation.UserID, ReservationHeld, ttl.Seconds()}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
+		&reservation.ID,
+		&reservation.CreatedAt,
+		&reservation.Status,
+		&reservation.ExpiresAt,
+		&reservation.Version,
+	)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO reservation_seats (showtime_id, seat, reservation_id)
+		SELECT $1, unnest($2::text[]), $3`
+
+	_, err = tx.ExecContext(ctx, query, reservation.ShowtimeID, pq.Array(reservation.Seats), reservation.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *ReservationModel) Get(id, userID int64) (*Reservation, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT r.id, r.created_at, r.showtime_id, r.user_id, r.status, r.expires_at, r.version,
+			COALESCE(array_agg(rs.seat ORDER BY rs.seat) FILTER (WHERE rs.seat IS NOT NULL), '{}')
+		FROM reservations r
+		LEFT JOIN reservation_seats rs ON rs.reservation_id = r.id
+		WHERE r.id = $1 AND r.user_id = $2
+		GROUP BY r.id`
+
+	var reservation Reservation
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&reservation.ID,
+		&reservation.CreatedAt,
+		&reservation.ShowtimeID,
+		&reservation.UserID,
+		&reservation.Status,
+		&reservation.ExpiresAt,
+		&reservation.Version,
+		pq.Array(&reservation.Seats),
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &reservation, nil
+}
+
+// Confirm turns a live hold into a booking. It fails with ErrHoldExpired when
+// the hold ran out before confirmation, even if the worker has not released
+// it yet.
+func (m *ReservationModel) Confirm(reservation *Reservation) error {
+	query := `
+		UPDATE reservations
+		SET status = $1, expires_at = NULL, version = version + 1
+		WHERE id = $2 AND user_id = $3 AND status = $4 AND expires_at > NOW()
+		RETURNING status, version`
+
+	args := []interface{}{ReservationConfirmed, reservation.ID, reservation.UserID, ReservationHeld}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&reservation.Status, &reservation.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if reservation.Status == ReservationHeld {
+				return ErrHoldExpired
+			}
+			return ErrInvalidReservationState
+		default:
+			return err
+		}
+	}
+
+	reservation.ExpiresAt = nil
+
+	return nil
+}
+
+// Cancel releases the seats of a held or confirmed reservation. It fails with
+// ErrShowtimeStarted once the showtime has begun.
+func (m *ReservationModel) Cancel(reservation *Reservation) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT st.starts_at <= NOW()
+		FROM reservations r
+		INNER JOIN showtimes st ON st.id = r.showtime_id
+		WHERE r.id = $1 AND r.user_id = $2
+		FOR UPDATE OF r`
+
+	var started bool
+
+	err = tx.QueryRowContext(ctx, query, reservation.ID, reservation.UserID).Scan(&started)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if started {
+		return ErrShowtimeStarted
+	}
+
+	query = `
+		UPDATE reservations
+		SET status = $1, expires_at = NULL, version = version + 1
+		WHERE id = $2 AND user_id = $3 AND status IN ($4, $5)
+		RETURNING status, version`
+
+	args := []interface{}{ReservationCancelled, reservation.ID, reservation.UserID, ReservationHeld, ReservationConfirmed}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&reservation.Status, &reservation.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrInvalidReservationState
+		default:
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM reservation_seats WHERE reservation_id = $1`, reservation.ID)
+	if err != nil {
+		return err
+	}
+
+	reservation.ExpiresAt = nil
+
+	return tx.Commit()
+}
+
+const releaseExpiredQuery = `
+	WITH expired AS (
+		UPDATE reservations
+		SET status = 'expired', version = version + 1
+		WHERE status = 'held' AND expires_at <= NOW()
+		AND (showtime_id = $1 OR $1 = 0)
+		RETURNING id
+	)
+	DELETE FROM reservation_seats
+	WHERE reservation_id IN (SELECT id FROM expired)`
+
+// ReleaseExpired frees the seats of every hold that has run out and returns
+// how many seats were released.
+func (m *ReservationModel) ReleaseExpired() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, releaseExpiredQuery, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -3,12 +3,35 @@ package data
 import (
 	"errors"
 	"fmt"
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
 var ErrInvalidRuntimeFormat = errors.New("invalid runtime format")
 
+var (
+	runtimeMinsRX  = regexp.MustCompile(`^(\d+)\s*mins?$`)
+	runtimeHumanRX = regexp.MustCompile(`^(?:(\d+)\s*h)?\s*(?:(\d+)\s*m)?$`)
+	runtimeISORX   = regexp.MustCompile(`^PT(?:(\d+)H)?(?:(\d+)M)?$`)
+)
+
+type RuntimeFormat string
+
+const (
+	RuntimeFormatDefault RuntimeFormat = ""
+	RuntimeFormatMinutes RuntimeFormat = "minutes"
+	RuntimeFormatHuman   RuntimeFormat = "human"
+	RuntimeFormatISO8601 RuntimeFormat = "iso8601"
+)
+
+var RuntimeFormats = []string{
+	string(RuntimeFormatMinutes),
+	string(RuntimeFormatHuman),
+	string(RuntimeFormatISO8601),
+}
+
 type Runtime int32
 
 func (r Runtime) MarshalJSON() ([]byte, error) {
@@ -17,19 +40,104 @@ func (r Runtime) MarshalJSON() ([]byte, error) {
 	return []byte(quotedJSONValue), nil
 }
 
+func (r Runtime) Format(format RuntimeFormat) interface{} {
+	hours, minutes := r/60, r%60
+
+	switch format {
+	case RuntimeFormatMinutes:
+		return int32(r)
+	case RuntimeFormatHuman:
+		switch {
+		case hours == 0:
+			return fmt.Sprintf("%dm", minutes)
+		case minutes == 0:
+			return fmt.Sprintf("%dh", hours)
+		default:
+			return fmt.Sprintf("%dh %dm", hours, minutes)
+		}
+	case RuntimeFormatISO8601:
+		switch {
+		case hours == 0:
+			return fmt.Sprintf("PT%dM", minutes)
+		case minutes == 0:
+			return fmt.Sprintf("PT%dH", hours)
+		default:
+			return fmt.Sprintf("PT%dH%dM", hours, minutes)
+		}
+	default:
+		return r
+	}
+}
+
 func (r *Runtime) UnmarshalJSON(jsonValue []byte) error {
-	unquotedJSONValue, err := strconv.Unquote(string(jsonValue))
-	if err != nil {
-		return ErrInvalidRuntimeFormat
+	value := strings.TrimSpace(string(jsonValue))
+	if value == "null" {
+		return nil
 	}
-	parts := strings.Split(unquotedJSONValue, " ")
-	if len(parts) != 2 || parts[1] != "mins" {
-		return ErrInvalidRuntimeFormat
+
+	if !strings.HasPrefix(value, `"`) {
+		i, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("%w: %s is not a whole number of minutes", ErrInvalidRuntimeFormat, value)
+		}
+		*r = Runtime(i)
+		return nil
 	}
-	i, err := strconv.ParseInt(parts[0], 10, 32)
+
+	unquotedJSONValue, err := strconv.Unquote(value)
 	if err != nil {
 		return ErrInvalidRuntimeFormat
 	}
+
+	i, err := parseRuntime(strings.TrimSpace(unquotedJSONValue))
+	if err != nil {
+		return err
+	}
 	*r = Runtime(i)
 	return nil
 }
+
+func parseRuntime(value string) (int64, error) {
+	if value == "" {
+		return 0, fmt.Errorf("%w: runtime must not be empty", ErrInvalidRuntimeFormat)
+	}
+
+	if matches := runtimeMinsRX.FindStringSubmatch(value); matches != nil {
+		return runtimeMinutes("0", matches[1], value)
+	}
+
+	if matches := runtimeISORX.FindStringSubmatch(value); matches != nil && value != "PT" {
+		return runtimeMinutes(matches[1], matches[2], value)
+	}
+
+	if matches := runtimeHumanRX.FindStringSubmatch(value); matches != nil && (matches[1] != "" || matches[2] != "") {
+		return runtimeMinutes(matches[1], matches[2], value)
+	}
+
+	return 0, fmt.Errorf(`%w: %q is not supported, use minutes (105), "105 mins", "1h 45m" or "PT1H45M"`, ErrInvalidRuntimeFormat, value)
+}
+
+func runtimeMinutes(hours, minutes, value string) (int64, error) {
+	var total int64
+
+	for _, part := range []struct {
+		digits string
+		scale  int64
+	}{{hours, 60}, {minutes, 1}} {
+		if part.digits == "" {
+			continue
+		}
+
+		n, err := strconv.ParseInt(part.digits, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q is too large", ErrInvalidRuntimeFormat, value)
+		}
+		total += n * part.scale
+	}
+
+	if total > math.MaxInt32 {
+		return 0, fmt.Errorf("%w: %q is too large", ErrInvalidRuntimeFormat, value)
+	}
+
+	return total, nil
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRuntimeUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Runtime
+	}{
+		{`105`, 105},
+		{`"105 mins"`, 105},
+		{`"1 min"`, 1},
+		{`"105mins"`, 105},
+		{`"1h 45m"`, 105},
+		{`"1h45m"`, 105},
+		{`"2h"`, 120},
+		{`"45m"`, 45},
+		{`"PT1H45M"`, 105},
+		{`"PT2H"`, 120},
+		{`"PT45M"`, 45},
+		{`" 1h 45m "`, 105},
+	}
+
+	for _, tt := range tests {
+		var r Runtime
+
+		err := r.UnmarshalJSON([]byte(tt.input))
+		if err != nil {
+			t.Errorf("UnmarshalJSON(%s): unexpected error: %v", tt.input, err)
+			continue
+		}
+
+		if r != tt.want {
+			t.Errorf("UnmarshalJSON(%s) = %d; want %d", tt.input, r, tt.want)
+		}
+	}
+}
+
+func TestRuntimeUnmarshalJSONNull(t *testing.T) {
+	r := Runtime(42)
+
+	err := r.UnmarshalJSON([]byte("null"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if r != 42 {
+		t.Errorf("null changed runtime to %d", r)
+	}
+}
+
+func TestRuntimeUnmarshalJSONInvalid(t *testing.T) {
+	tests := []string{
+		`""`,
+		`"PT"`,
+		`"abc"`,
+		`"1.5h"`,
+		`"105 hours"`,
+		`1.5`,
+		`"99999999999 mins"`,
+		`"PT99999999H"`,
+		`"105`,
+	}
+
+	for _, input := range tests {
+		var r Runtime
+
+		err := r.UnmarshalJSON([]byte(input))
+		if !errors.Is(err, ErrInvalidRuntimeFormat) {
+			t.Errorf("UnmarshalJSON(%s) error = %v; want ErrInvalidRuntimeFormat", input, err)
+		}
+	}
+}
+
+func TestRuntimeFormat(t *testing.T) {
+	tests := []struct {
+		runtime Runtime
+		format  RuntimeFormat
+		want    interface{}
+	}{
+		{105, RuntimeFormatMinutes, int32(105)},
+		{105, RuntimeFormatHuman, "1h 45m"},
+		{120, RuntimeFormatHuman, "2h"},
+		{45, RuntimeFormatHuman, "45m"},
+		{105, RuntimeFormatISO8601, "PT1H45M"},
+		{120, RuntimeFormatISO8601, "PT2H"},
+		{45, RuntimeFormatISO8601, "PT45M"},
+		{105, RuntimeFormatDefault, Runtime(105)},
+	}
+
+	for _, tt := range tests {
+		got := tt.runtime.Format(tt.format)
+		if got != tt.want {
+			t.Errorf("Runtime(%d).Format(%q) = %v (%T); want %v (%T)", tt.runtime, tt.format, got, got, tt.want, tt.want)
+		}
+	}
+}
+
+func TestRuntimeMarshalJSON(t *testing.T) {
+	b, err := Runtime(105).MarshalJSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if string(b) != `"105 mins"` {
+		t.Errorf("MarshalJSON = %s; want %q", b, "105 mins")
+	}
+}
@@ -0,0 +1,232 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+var (
+	ErrDuplicateName = errors.New("duplicate name")
+
+	SeatRowRX = regexp.MustCompile(`^[A-Z]{1,2}$`)
+)
+
+// SeatRow describes one row of seats, numbered from 1 to Seats. A seat is
+// addressed by its row label followed by its number, e.g. "C7".
+type SeatRow struct {
+	Row   string `json:"row"`
+	Seats int32  `json:"seats"`
+}
+
+type SeatMap []SeatRow
+
+func (m SeatMap) Capacity() int32 {
+	var capacity int32
+	for _, row := range m {
+		capacity += row.Seats
+	}
+	return capacity
+}
+
+func (m SeatMap) Has(seat string) bool {
+	label := strings.TrimRight(seat, "0123456789")
+	number, err := strconv.Atoi(seat[len(label):])
+	if err != nil || strings.HasPrefix(seat[len(label):], "0") {
+		return false
+	}
+
+	for _, row := range m {
+		if row.Row == label {
+			return number >= 1 && number <= int(row.Seats)
+		}
+	}
+	return false
+}
+
+func (m SeatMap) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+func (m *SeatMap) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, m)
+	case string:
+		return json.Unmarshal([]byte(src), m)
+	default:
+		return fmt.Errorf("cannot scan %T into SeatMap", src)
+	}
+}
+
+type Screen struct {
+	ID        int64     `json:"id"`
+	CinemaID  int64     `json:"cinema_id"`
+	Name      string    `json:"name"`
+	SeatMap   SeatMap   `json:"seat_map"`
+	Capacity  int32     `json:"capacity"`
+	Version   int32     `json:"version"`
+	CreatedAt time.Time `json:"-"`
+}
+
+func ValidateScreen(v *validator.Validator, screen *Screen) {
+	v.Check(screen.Name != "", "name", "must be provided")
+	v.Check(len(screen.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(len(screen.SeatMap) >= 1, "seat_map", "must contain at least 1 row")
+	v.Check(len(screen.SeatMap) <= 100, "seat_map", "must not contain more than 100 rows")
+
+	labels := make([]string, len(screen.SeatMap))
+	for i, row := range screen.SeatMap {
+		labels[i] = row.Row
+		v.Check(validator.Matches(row.Row, SeatRowRX), "seat_map", "row labels must be one or two capital letters")
+		v.Check(row.Seats >= 1 && row.Seats <= 200, "seat_map", "rows must have between 1 and 200 seats")
+	}
+	v.Check(validator.Unique(labels), "seat_map", "must not contain duplicate row labels")
+}
+
+type ScreenModel struct {
+	DB *sql.DB
+}
+
+func (m *ScreenModel) Insert(screen *Screen) error {
+	query := `
+		INSERT INTO screens (cinema_id, name, seat_map, capacity)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, version`
+
+	screen.Capacity = screen.SeatMap.Capacity()
+
+	args := []interface{}{screen.CinemaID, screen.Name, screen.SeatMap, screen.Capacity}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&screen.ID, &screen.CreatedAt, &screen.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "screens_cinema_id_name_key"):
+			return ErrDuplicateName
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *ScreenModel) Get(id int64) (*Screen, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, created_at, cinema_id, name, seat_map, capacity, version
+		FROM screens
+		WHERE id = $1`
+
+	var screen Screen
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&screen.ID,
+		&screen.CreatedAt,
+		&screen.CinemaID,
+		&screen.Name,
+		&screen.SeatMap,
+		&screen.Capacity,
+		&screen.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &screen, nil
+}
+
+func (m *ScreenModel) GetAllForCinema(cinemaID int64) ([]*Screen, error) {
+	query := `
+		SELECT id, created_at, cinema_id, name, seat_map, capacity, version
+		FROM screens
+		WHERE cinema_id = $1
+		ORDER BY name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, cinemaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	screens := []*Screen{}
+
+	for rows.Next() {
+		var screen Screen
+
+		err := rows.Scan(
+			&screen.ID,
+			&screen.CreatedAt,
+			&screen.CinemaID,
+			&screen.Name,
+			&screen.SeatMap,
+			&screen.Capacity,
+			&screen.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		screens = append(screens, &screen)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return screens, nil
+}
+
+func (m *ScreenModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM screens WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	SearchTypeMovie  = "movie"
+	SearchTypeSeries = "series"
+)
+
+type SearchResult struct {
+	Type   string   `json:"type"`
+	ID     int64    `json:"id"`
+	Title  string   `json:"title"`
+	Year   int32    `json:"year"`
+	Genres []string `json:"genres,omitempty"`
+}
+
+type SearchModel struct {
+	DB *sql.DB
+}
+
+func (m SearchModel) Search(q, resultType string, filters Filters) ([]*SearchResult, Metadata, error) {
+	query := `
+		SELECT count(*) OVER(), type, id, title, year, genres
+		FROM (
+			SELECT 'movie' AS type, id, title, year, genres,
+				ts_rank(to_tsvector('simple', title), plainto_tsquery('simple', $1)) AS rank
+			FROM movies
+			WHERE status = 'published'
+			AND to_tsvector('simple', title) @@ plainto_tsquery('simple', $1)
+			UNION ALL
+			SELECT 'series' AS type, id, title, start_year, genres,
+				ts_rank(to_tsvector('simple', title), plainto_tsquery('simple', $1)) AS rank
+			FROM series
+			WHERE to_tsvector('simple', title) @@ plainto_tsquery('simple', $1)
+		) AS results
+		WHERE (type = $2 OR $2 = '')
+		ORDER BY rank DESC, title ASC, type ASC, id ASC
+		LIMIT $3 OFFSET $4`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, q, resultType, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	results := []*SearchResult{}
+
+	for rows.Next() {
+		var result SearchResult
+
+		err := rows.Scan(
+			&totalRecords,
+			&result.Type,
+			&result.ID,
+			&result.Title,
+			&result.Year,
+			pq.Array(&result.Genres),
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		results = append(results, &result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return results, metadata, nil
+}
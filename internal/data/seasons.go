@@ -0,0 +1,153 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+var ErrDuplicateNumber = errors.New("duplicate number")
+
+type Season struct {
+	ID        int64      `json:"id"`
+	SeriesID  int64      `json:"series_id"`
+	Number    int32      `json:"number"`
+	Title     string     `json:"title,omitempty"`
+	Episodes  []*Episode `json:"episodes,omitempty"`
+	Version   int32      `json:"version"`
+	CreatedAt time.Time  `json:"-"`
+}
+
+func ValidateSeason(v *validator.Validator, season *Season) {
+	v.Check(season.Number >= 0, "number", "must not be negative")
+	v.Check(season.Number <= 1000, "number", "must not be more than 1000")
+	v.Check(len(season.Title) <= 500, "title", "must not be more than 500 bytes long")
+}
+
+type SeasonModel struct {
+	DB *sql.DB
+}
+
+func (m *SeasonModel) Insert(season *Season) error {
+	query := `
+		INSERT INTO seasons (series_id, number, title)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, version`
+
+	args := []interface{}{season.SeriesID, season.Number, season.Title}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&season.ID, &season.CreatedAt, &season.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "seasons_series_id_number_key"):
+			return ErrDuplicateNumber
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *SeasonModel) Get(seriesID int64, number int32) (*Season, error) {
+	query := `
+		SELECT id, created_at, series_id, number, title, version
+		FROM seasons
+		WHERE series_id = $1 AND number = $2`
+
+	var season Season
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, seriesID, number).Scan(
+		&season.ID,
+		&season.CreatedAt,
+		&season.SeriesID,
+		&season.Number,
+		&season.Title,
+		&season.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &season, nil
+}
+
+func (m *SeasonModel) GetAllForSeries(seriesID int64) ([]*Season, error) {
+	query := `
+		SELECT id, created_at, series_id, number, title, version
+		FROM seasons
+		WHERE series_id = $1
+		ORDER BY number`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seasons := []*Season{}
+
+	for rows.Next() {
+		var season Season
+
+		err := rows.Scan(
+			&season.ID,
+			&season.CreatedAt,
+			&season.SeriesID,
+			&season.Number,
+			&season.Title,
+			&season.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		seasons = append(seasons, &season)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return seasons, nil
+}
+
+func (m *SeasonModel) Delete(id int64) error {
+	query := `DELETE FROM seasons WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
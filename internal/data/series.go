@@ -0,0 +1,209 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/validator"
+	"github.com/lib/pq"
+)
+
+type Series struct {
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	StartYear int32     `json:"start_year"`
+	EndYear   int32     `json:"end_year,omitempty"`
+	Genres    []string  `json:"genres,omitempty"`
+	Seasons   []*Season `json:"seasons,omitempty"`
+	Version   int32     `json:"version"`
+	CreatedAt time.Time `json:"-"`
+}
+
+func ValidateSeries(v *validator.Validator, series *Series) {
+	v.Check(series.Title != "", "title", "must be provided")
+	v.Check(len(series.Title) <= 500, "title", "must not be more than 500 bytes long")
+
+	v.Check(series.StartYear != 0, "start_year", "must be provided")
+	v.Check(series.StartYear >= 1888, "start_year", "must be greater than 1888")
+	v.Check(series.StartYear <= int32(time.Now().Year()), "start_year", "must not be in the future")
+
+	if series.EndYear != 0 {
+		v.Check(series.EndYear >= series.StartYear, "end_year", "must not be before start_year")
+		v.Check(series.EndYear <= int32(time.Now().Year()), "end_year", "must not be in the future")
+	}
+
+	v.Check(series.Genres != nil, "genres", "must be provided")
+	v.Check(len(series.Genres) >= 1, "genres", "must contain at least 1 genre")
+	v.Check(len(series.Genres) <= 5, "genres", "must not contain more than 5 genres")
+
+	v.Check(validator.Unique(series.Genres), "genres", "must not contain duplicate values")
+}
+
+type SeriesModel struct {
+	DB *sql.DB
+}
+
+func (m *SeriesModel) Insert(series *Series) error {
+	query := `
+		INSERT INTO series (title, start_year, end_year, genres)
+		VALUES ($1, $2, NULLIF($3, 0), $4)
+		RETURNING id, created_at, version`
+
+	args := []interface{}{series.Title, series.StartYear, series.EndYear, pq.Array(series.Genres)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&series.ID, &series.CreatedAt, &series.Version)
+}
+
+func (m *SeriesModel) Get(id int64) (*Series, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, created_at, title, start_year, COALESCE(end_year, 0), genres, version
+		FROM series
+		WHERE id = $1`
+
+	var series Series
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&series.ID,
+		&series.CreatedAt,
+		&series.Title,
+		&series.StartYear,
+		&series.EndYear,
+		pq.Array(&series.Genres),
+		&series.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &series, nil
+}
+
+func (m *SeriesModel) Update(series *Series) error {
+	query := `
+		UPDATE series
+		SET title = $1, start_year = $2, end_year = NULLIF($3, 0), genres = $4, version = version + 1
+		WHERE id = $5 AND version = $6
+		RETURNING version`
+
+	args := []interface{}{
+		series.Title,
+		series.StartYear,
+		series.EndYear,
+		pq.Array(series.Genres),
+		series.ID,
+		series.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&series.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *SeriesModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM series WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *SeriesModel) GetAll(title string, genres []string, filters Filters) ([]*Series, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, title, start_year, COALESCE(end_year, 0), genres, version
+	FROM series
+	WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
+	AND (genres @> $2 OR $2 = '{}')
+	ORDER BY %s %s, id ASC
+	LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{title, pq.Array(genres), filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	list := []*Series{}
+
+	for rows.Next() {
+		var series Series
+
+		err := rows.Scan(
+			&totalRecords,
+			&series.ID,
+			&series.CreatedAt,
+			&series.Title,
+			&series.StartYear,
+			&series.EndYear,
+			pq.Array(&series.Genres),
+			&series.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		list = append(list, &series)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return list, metadata, nil
+}
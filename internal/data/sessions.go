@@ -0,0 +1,138 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const maxUserAgentLength = 512
+
+// Session describes a signed in client without revealing its tokens.
+type Session struct {
+	ID         int64      `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Expiry     time.Time  `json:"expiry"`
+	IP         string     `json:"ip,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	Current    bool       `json:"current"`
+}
+
+// TruncateUserAgent keeps user agents within the length stored for a session.
+func TruncateUserAgent(userAgent string) string {
+	if len(userAgent) > maxUserAgentLength {
+		return userAgent[:maxUserAgentLength]
+	}
+	return userAgent
+}
+
+// GetAllSessions returns one entry per signed in client, newest first: the
+// unused refresh token of each token family, plus authentication tokens that
+// were issued without one. The session holding currentHash, or belonging to
+// currentFamily, is flagged as current.
+func (m *TokenModel) GetAllSessions(userID int64, currentHash, currentFamily []byte) ([]*Session, error) {
+	query := `
+	    SELECT t.id, t.created_at,
+	        (SELECT max(f.last_used_at) FROM tokens f WHERE f.hash = t.hash OR f.family = t.family),
+	        t.expiry, t.ip, t.user_agent,
+	        COALESCE(t.hash = $2 OR t.family = $3 OR t.family = (SELECT family FROM tokens WHERE hash = $2), false)
+	    FROM tokens t
+	    WHERE t.user_id = $1 AND t.expiry > NOW()
+	    AND ((t.scope = $4 AND t.family IS NULL) OR (t.scope = $5 AND t.used_at IS NULL))
+	    ORDER BY t.created_at DESC, t.id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, currentHash, currentFamily, ScopeAuthentication, ScopeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+
+	for rows.Next() {
+		var session Session
+
+		err := rows.Scan(
+			&session.ID,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.Expiry,
+			&session.IP,
+			&session.UserAgent,
+			&session.Current,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, &session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (m *TokenModel) DeleteSession(id, userID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+	    DELETE FROM tokens
+	    WHERE user_id = $2
+	    AND ((id = $1 AND scope IN ($3, $4)) OR family = (SELECT family FROM tokens WHERE id = $1 AND scope IN ($3, $4)))`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID, ScopeAuthentication, ScopeRefresh)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Touch records when each token, keyed by its hash, was last used.
+func (m *TokenModel) Touch(used map[string]time.Time) error {
+	if len(used) == 0 {
+		return nil
+	}
+
+	hashes := make([][]byte, 0, len(used))
+	times := make([]int64, 0, len(used))
+	for hash, usedAt := range used {
+		hashes = append(hashes, []byte(hash))
+		times = append(times, usedAt.Unix())
+	}
+
+	query := `
+	    UPDATE tokens
+	    SET last_used_at = to_timestamp(u.used_at)
+	    FROM unnest($1::bytea[], $2::bigint[]) AS u(hash, used_at)
+	    WHERE tokens.hash = u.hash
+	    AND (tokens.last_used_at IS NULL OR tokens.last_used_at < to_timestamp(u.used_at))`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, pq.Array(hashes), pq.Array(times))
+	return err
+}
@@ -0,0 +1,307 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+var ErrShowtimeOverlap = errors.New("showtime overlaps another showtime on the same screen")
+
+type Showtime struct {
+	ID         int64     `json:"id"`
+	MovieID    int64     `json:"movie_id"`
+	ScreenID   int64     `json:"screen_id"`
+	ScreenName string    `json:"screen_name,omitempty"`
+	CinemaID   int64     `json:"cinema_id"`
+	CinemaName string    `json:"cinema_name,omitempty"`
+	Timezone   string    `json:"timezone,omitempty"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	Version    int32     `json:"version"`
+	CreatedAt  time.Time `json:"-"`
+}
+
+func ValidateShowtime(v *validator.Validator, showtime *Showtime, runtime Runtime) {
+	v.Check(showtime.MovieID > 0, "movie_id", "must be provided")
+	v.Check(showtime.ScreenID > 0, "screen_id", "must be provided")
+
+	v.Check(!showtime.StartsAt.IsZero(), "starts_at", "must be provided")
+	v.Check(showtime.StartsAt.After(time.Now()), "starts_at", "must be in the future")
+
+	v.Check(runtime > 0, "movie_id", "movie must have a runtime to be scheduled")
+}
+
+// ShowtimeQuery selects showtimes whose start falls within Days calendar days
+// from Date, where days are counted in each cinema's own time zone. A zero
+// Date means today in each cinema's time zone.
+type ShowtimeQuery struct {
+	MovieID  int64
+	CinemaID int64
+	City     string
+	Date     time.Time
+	Days     int
+
+	ViewerID           int64
+	IncludeUnpublished bool
+}
+
+type ShowtimeModel struct {
+	DB *sql.DB
+}
+
+// Insert schedules the showtime, rejecting it with ErrShowtimeOverlap when the
+// screen is already busy within buffer of its start or end. The screen row is
+// locked for the duration of the check so that concurrent inserts for the same
+// screen are serialized.
+func (m *ShowtimeModel) Insert(showtime *Showtime, buffer time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT s.name, c.id, c.name, c.timezone
+		FROM screens s
+		INNER JOIN cinemas c ON c.id = s.cinema_id
+		WHERE s.id = $1
+		FOR UPDATE OF s`
+
+	err = tx.QueryRowContext(ctx, query, showtime.ScreenID).Scan(
+		&showtime.ScreenName,
+		&showtime.CinemaID,
+		&showtime.CinemaName,
+		&showtime.Timezone,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	query = `
+		SELECT EXISTS (
+			SELECT 1
+			FROM showtimes
+			WHERE screen_id = $1
+			AND starts_at < $3
+			AND ends_at > $2
+		)`
+
+	var overlaps bool
+
+	err = tx.QueryRowContext(ctx, query, showtime.ScreenID, showtime.StartsAt.Add(-buffer), showtime.EndsAt.Add(buffer)).Scan(&overlaps)
+	if err != nil {
+		return err
+	}
+
+	if overlaps {
+		return ErrShowtimeOverlap
+	}
+
+	query = `
+		INSERT INTO showtimes (movie_id, screen_id, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, version`
+
+	args := []interface{}{showtime.MovieID, showtime.ScreenID, showtime.StartsAt, showtime.EndsAt}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&showtime.ID, &showtime.CreatedAt, &showtime.Version)
+	if err != nil {
+		return err
+	}
+
+	showtime.localize()
+
+	return tx.Commit()
+}
+
+// Get looks up a showtime of a movie the viewer may see, which is a published
+// one, one they created, or any when includeUnpublished is set.
+func (m *ShowtimeModel) Get(id, viewerID int64, includeUnpublished bool) (*Showtime, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT st.id, st.created_at, st.movie_id, st.screen_id, s.name, c.id, c.name, c.timezone, st.starts_at, st.ends_at, st.version
+		FROM showtimes st
+		INNER JOIN screens s ON s.id = st.screen_id
+		INNER JOIN cinemas c ON c.id = s.cinema_id
+		INNER JOIN movies m ON m.id = st.movie_id
+		WHERE st.id = $1
+		AND (m.status = 'published' OR $2 OR m.created_by = $3)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	showtime, err := scanShowtime(m.DB.QueryRowContext(ctx, query, id, includeUnpublished, viewerID))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return showtime, nil
+}
+
+func (m *ShowtimeModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM showtimes WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *ShowtimeModel) GetAll(q ShowtimeQuery, filters Filters) ([]*Showtime, Metadata, error) {
+	// The first range on starts_at is a coarse, index-friendly bound wide
+	// enough to cover every UTC offset; the second one applies the exact
+	// calendar days in the cinema's time zone.
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), st.id, st.created_at, st.movie_id, st.screen_id, s.name, c.id, c.name, c.timezone, st.starts_at, st.ends_at, st.version
+	FROM showtimes st
+	INNER JOIN screens s ON s.id = st.screen_id
+	INNER JOIN cinemas c ON c.id = s.cinema_id
+	INNER JOIN movies m ON m.id = st.movie_id
+	WHERE (st.movie_id = $1 OR $1 = 0)
+	AND (c.id = $2 OR $2 = 0)
+	AND (lower(c.city) = lower($3) OR $3 = '')
+	AND (m.status = 'published' OR $8 OR m.created_by = $9)
+	AND st.starts_at >= COALESCE($4::date, CURRENT_DATE) - interval '2 days'
+	AND st.starts_at < COALESCE($4::date, CURRENT_DATE) + $5::int + interval '2 days'
+	AND (st.starts_at AT TIME ZONE c.timezone)::date >= COALESCE($4::date, (NOW() AT TIME ZONE c.timezone)::date)
+	AND (st.starts_at AT TIME ZONE c.timezone)::date < COALESCE($4::date, (NOW() AT TIME ZONE c.timezone)::date) + $5::int
+	ORDER BY %s %s, st.id ASC
+	LIMIT $6 OFFSET $7`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var date interface{}
+	if !q.Date.IsZero() {
+		date = q.Date.Format(dateLayout)
+	}
+
+	args := []interface{}{
+		q.MovieID,
+		q.CinemaID,
+		q.City,
+		date,
+		q.Days,
+		filters.limit(),
+		filters.offset(),
+		q.IncludeUnpublished,
+		q.ViewerID,
+	}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	showtimes := []*Showtime{}
+
+	for rows.Next() {
+		var showtime Showtime
+
+		err := rows.Scan(
+			&totalRecords,
+			&showtime.ID,
+			&showtime.CreatedAt,
+			&showtime.MovieID,
+			&showtime.ScreenID,
+			&showtime.ScreenName,
+			&showtime.CinemaID,
+			&showtime.CinemaName,
+			&showtime.Timezone,
+			&showtime.StartsAt,
+			&showtime.EndsAt,
+			&showtime.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		showtime.localize()
+		showtimes = append(showtimes, &showtime)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return showtimes, metadata, nil
+}
+
+func scanShowtime(row rowScanner) (*Showtime, error) {
+	var showtime Showtime
+
+	err := row.Scan(
+		&showtime.ID,
+		&showtime.CreatedAt,
+		&showtime.MovieID,
+		&showtime.ScreenID,
+		&showtime.ScreenName,
+		&showtime.CinemaID,
+		&showtime.CinemaName,
+		&showtime.Timezone,
+		&showtime.StartsAt,
+		&showtime.EndsAt,
+		&showtime.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	showtime.localize()
+
+	return &showtime, nil
+}
+
+// localize expresses the start and end times in the cinema's time zone, which
+// is what moviegoers expect to see.
+func (s *Showtime) localize() {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return
+	}
+	s.StartsAt = s.StartsAt.In(loc)
+	s.EndsAt = s.EndsAt.In(loc)
+}
@@ -0,0 +1,83 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SigningKey is a private key used to sign JWT access tokens, stored as
+// PKCS #8 DER.
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey []byte
+	CreatedAt  time.Time
+}
+
+type SigningKeyModel struct {
+	DB *sql.DB
+}
+
+func (m SigningKeyModel) Insert(key *SigningKey) error {
+	query := `
+		INSERT INTO signing_keys (id, algorithm, private_key)
+		VALUES ($1, $2, $3)
+		RETURNING created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, key.ID, key.Algorithm, key.PrivateKey).Scan(&key.CreatedAt)
+}
+
+// GetAll returns every stored key, newest first.
+func (m SigningKeyModel) GetAll() ([]*SigningKey, error) {
+	query := `
+		SELECT id, algorithm, private_key, created_at
+		FROM signing_keys
+		ORDER BY created_at DESC, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*SigningKey{}
+
+	for rows.Next() {
+		var key SigningKey
+
+		err := rows.Scan(&key.ID, &key.Algorithm, &key.PrivateKey, &key.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, &key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// DeleteRetired removes keys created before the cutoff, except the newest key,
+// which is still signing tokens.
+func (m SigningKeyModel) DeleteRetired(cutoff time.Time) error {
+	query := `
+		DELETE FROM signing_keys
+		WHERE created_at < $1
+		AND id <> (SELECT id FROM signing_keys ORDER BY created_at DESC, id LIMIT 1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, cutoff)
+	return err
+}
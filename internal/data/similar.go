@@ -0,0 +1,226 @@
+package data
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	similarCandidateLimit = 1000
+	similarYearWindow     = 30
+)
+
+type SimilarityWeights struct {
+	Genres  float64
+	Year    float64
+	Runtime float64
+}
+
+type SimilarMovie struct {
+	Movie *Movie  `json:"movie"`
+	Score float64 `json:"score"`
+}
+
+func (w SimilarityWeights) Score(a, b *Movie) float64 {
+	total := w.Genres + w.Year + w.Runtime
+	if total <= 0 {
+		return 0
+	}
+
+	score := w.Genres*genreSimilarity(a.Genres, b.Genres) +
+		w.Year*yearSimilarity(a.Year, b.Year) +
+		w.Runtime*runtimeSimilarity(a.Runtime, b.Runtime)
+
+	return score / total
+}
+
+func genreSimilarity(a, b []string) float64 {
+	set := make(map[string]bool, len(a))
+	for _, genre := range a {
+		set[genre] = true
+	}
+
+	intersection := 0
+	union := len(set)
+	for _, genre := range b {
+		inA, seen := set[genre]
+		switch {
+		case inA:
+			intersection++
+		case !seen:
+			union++
+		}
+		set[genre] = false
+	}
+
+	if union == 0 {
+		return 0
+	}
+	return float64(intersection) / float64(union)
+}
+
+func yearSimilarity(a, b int32) float64 {
+	diff := math.Abs(float64(a - b))
+	return math.Max(0, 1-diff/similarYearWindow)
+}
+
+func runtimeSimilarity(a, b Runtime) float64 {
+	longest := math.Max(float64(a), float64(b))
+	if longest <= 0 {
+		return 0
+	}
+	return 1 - math.Abs(float64(a-b))/longest
+}
+
+func RankSimilar(movie *Movie, candidates []*Movie, weights SimilarityWeights) []*SimilarMovie {
+	ranked := make([]*SimilarMovie, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.ID == movie.ID {
+			continue
+		}
+		ranked = append(ranked, &SimilarMovie{Movie: candidate, Score: weights.Score(movie, candidate)})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Movie.ID < ranked[j].Movie.ID
+	})
+
+	return ranked
+}
+
+// SimilarRanking is the outcome of RankSimilar without the movies themselves,
+// so that it can be cached and the movies looked up fresh on every request.
+type SimilarRanking struct {
+	IDs    []int64
+	Scores []float64
+}
+
+func NewSimilarRanking(ranked []*SimilarMovie) SimilarRanking {
+	ranking := SimilarRanking{
+		IDs:    make([]int64, len(ranked)),
+		Scores: make([]float64, len(ranked)),
+	}
+
+	for i, similar := range ranked {
+		ranking.IDs[i] = similar.Movie.ID
+		ranking.Scores[i] = similar.Score
+	}
+
+	return ranking
+}
+
+// GetRanked returns a page of the ranked movies in rank order, skipping the
+// ones that have been deleted or are no longer published since they were
+// ranked.
+func (m *MovieModel) GetRanked(ranking SimilarRanking, filters Filters) ([]*SimilarMovie, Metadata, error) {
+	query := `
+		SELECT count(*) OVER(), m.id, m.created_at, m.title, m.year, m.runtime, m.genres, m.status, m.version, r.score
+		FROM unnest($1::bigint[], $2::float8[]) WITH ORDINALITY AS r(id, score, rank)
+		INNER JOIN movies m ON m.id = r.id
+		WHERE m.status = 'published'
+		ORDER BY r.rank
+		LIMIT $3 OFFSET $4`
+
+	args := []interface{}{pq.Array(ranking.IDs), pq.Array(ranking.Scores), filters.limit(), filters.offset()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	movies := []*SimilarMovie{}
+
+	for rows.Next() {
+		var similar SimilarMovie
+		similar.Movie = &Movie{}
+
+		err := rows.Scan(
+			&totalRecords,
+			&similar.Movie.ID,
+			&similar.Movie.CreatedAt,
+			&similar.Movie.Title,
+			&similar.Movie.Year,
+			&similar.Movie.Runtime,
+			pq.Array(&similar.Movie.Genres),
+			&similar.Movie.Status,
+			&similar.Movie.Version,
+			&similar.Score,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		movies = append(movies, &similar)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return movies, metadata, nil
+}
+
+func (m *MovieModel) GetSimilarCandidates(movie *Movie) ([]*Movie, error) {
+	query := `
+		SELECT id, created_at, title, year, runtime, genres, status, version
+		FROM movies
+		WHERE id <> $1
+		AND status = 'published'
+		AND genres && $2
+		AND year BETWEEN $3 - $4 AND $3 + $4
+		ORDER BY abs(year - $3), id
+		LIMIT $5`
+
+	args := []interface{}{movie.ID, pq.Array(movie.Genres), movie.Year, similarYearWindow, similarCandidateLimit}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movies := []*Movie{}
+
+	for rows.Next() {
+		var movie Movie
+
+		err := rows.Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Status,
+			&movie.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movies, nil
+}
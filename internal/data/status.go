@@ -0,0 +1,96 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	StatusDraft     = "draft"
+	StatusReview    = "review"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+var Statuses = []string{StatusDraft, StatusReview, StatusPublished, StatusArchived}
+
+var statusTransitions = map[string][]string{
+	StatusDraft:     {StatusReview},
+	StatusReview:    {StatusDraft, StatusPublished},
+	StatusPublished: {StatusArchived},
+	StatusArchived:  {StatusDraft},
+}
+
+func CanTransition(from, to string) bool {
+	for _, status := range statusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves the movie to the given status. Publishing with a
+// publishAt in the future keeps the movie in review and schedules it
+// for PublishScheduled instead.
+func (m *MovieModel) Transition(movie *Movie, to string, publishAt *time.Time) error {
+	if !CanTransition(movie.Status, to) {
+		return ErrInvalidTransition
+	}
+
+	if publishAt != nil && to != StatusPublished {
+		return ErrInvalidTransition
+	}
+
+	if publishAt != nil && publishAt.After(time.Now()) {
+		to = StatusReview
+	} else {
+		publishAt = nil
+	}
+
+	query := `
+		UPDATE movies
+		SET status = $1, publish_at = $2, updated_by = $3, version = version + 1
+		WHERE id = $4 AND version = $5 AND status = $6
+		RETURNING version`
+
+	args := []interface{}{to, publishAt, movie.UpdatedBy, movie.ID, movie.Version, movie.Status}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	movie.Status = to
+	movie.PublishAt = publishAt
+	return nil
+}
+
+func (m *MovieModel) PublishScheduled() (int64, error) {
+	query := `
+		UPDATE movies
+		SET status = 'published', publish_at = NULL, version = version + 1
+		WHERE status = 'review' AND publish_at <= NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
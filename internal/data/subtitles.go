@@ -0,0 +1,183 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/validator"
+)
+
+var LanguageRX = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+type Subtitle struct {
+	ID        int64     `json:"id"`
+	MovieID   int64     `json:"movie_id"`
+	Language  string    `json:"language"`
+	CueCount  int       `json:"cue_count"`
+	BlobKey   string    `json:"-"`
+	Text      string    `json:"-"`
+	CreatedBy int64     `json:"created_by,omitempty"`
+	Version   int32     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func ValidateLanguage(v *validator.Validator, language string) {
+	v.Check(language != "", "language", "must be provided")
+	v.Check(len(language) <= 35, "language", "must not be more than 35 bytes long")
+	v.Check(validator.Matches(language, LanguageRX), "language", "must be a language tag such as en or pt-BR")
+}
+
+type SubtitleModel struct {
+	DB *sql.DB
+}
+
+// Upsert stores the subtitle track, replacing any existing track in the same
+// language. It returns the blob key of the replaced track, if there was one.
+// A replaced track keeps its original creator, and a track created by someone
+// else is only replaced when replaceOthers is set; otherwise ErrEditConflict
+// is returned.
+func (m SubtitleModel) Upsert(subtitle *Subtitle, replaceOthers bool) (string, error) {
+	query := `
+		WITH previous AS (
+			SELECT blob_key FROM subtitles WHERE movie_id = $1 AND language = $2
+		)
+		INSERT INTO subtitles (movie_id, language, cue_count, blob_key, text, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (movie_id, language) DO UPDATE
+		SET cue_count = EXCLUDED.cue_count, blob_key = EXCLUDED.blob_key, text = EXCLUDED.text,
+			updated_at = NOW(), version = subtitles.version + 1
+		WHERE subtitles.created_by = EXCLUDED.created_by OR $7
+		RETURNING id, COALESCE(created_by, 0), updated_at, version, COALESCE((SELECT blob_key FROM previous), '')`
+
+	args := []interface{}{
+		subtitle.MovieID,
+		subtitle.Language,
+		subtitle.CueCount,
+		subtitle.BlobKey,
+		subtitle.Text,
+		subtitle.CreatedBy,
+		replaceOthers,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var previousKey string
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&subtitle.ID, &subtitle.CreatedBy, &subtitle.UpdatedAt, &subtitle.Version, &previousKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", ErrEditConflict
+		default:
+			return "", err
+		}
+	}
+
+	return previousKey, nil
+}
+
+func (m SubtitleModel) Get(movieID int64, language string) (*Subtitle, error) {
+	query := `
+		SELECT id, movie_id, language, cue_count, blob_key, COALESCE(created_by, 0), version, updated_at
+		FROM subtitles
+		WHERE movie_id = $1 AND language = $2`
+
+	var subtitle Subtitle
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, movieID, language).Scan(
+		&subtitle.ID,
+		&subtitle.MovieID,
+		&subtitle.Language,
+		&subtitle.CueCount,
+		&subtitle.BlobKey,
+		&subtitle.CreatedBy,
+		&subtitle.Version,
+		&subtitle.UpdatedAt,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &subtitle, nil
+}
+
+func (m SubtitleModel) GetAllForMovie(movieID int64) ([]*Subtitle, error) {
+	query := `
+		SELECT id, movie_id, language, cue_count, blob_key, COALESCE(created_by, 0), version, updated_at
+		FROM subtitles
+		WHERE movie_id = $1
+		ORDER BY language`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subtitles := []*Subtitle{}
+
+	for rows.Next() {
+		var subtitle Subtitle
+
+		err := rows.Scan(
+			&subtitle.ID,
+			&subtitle.MovieID,
+			&subtitle.Language,
+			&subtitle.CueCount,
+			&subtitle.BlobKey,
+			&subtitle.CreatedBy,
+			&subtitle.Version,
+			&subtitle.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		subtitles = append(subtitles, &subtitle)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subtitles, nil
+}
+
+func (m SubtitleModel) Delete(id int64) error {
+	query := `DELETE FROM subtitles WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
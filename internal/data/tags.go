@@ -0,0 +1,273 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/SemmiDev/chimovies/internal/validator"
+	"github.com/lib/pq"
+)
+
+// NormalizeTag lowercases the tag and reduces every run of characters other
+// than letters, digits, hyphens and apostrophes to a single space, so that
+// "Time Travel", "time  travel!" and " TIME TRAVEL" do not become three tags.
+func NormalizeTag(tag string) string {
+	var b strings.Builder
+	space := false
+
+	for _, r := range strings.ToLower(tag) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '\'' {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+			continue
+		}
+		space = true
+	}
+
+	return b.String()
+}
+
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
+
+func ValidateTags(v *validator.Validator, tags []string) {
+	v.Check(len(tags) >= 1, "tags", "must contain at least 1 tag")
+	v.Check(len(tags) <= 10, "tags", "must not contain more than 10 tags")
+
+	for _, tag := range tags {
+		v.Check(len(tag) >= 2, "tags", "must not contain tags shorter than 2 bytes")
+		v.Check(len(tag) <= 50, "tags", "must not contain tags longer than 50 bytes")
+	}
+}
+
+type MovieTag struct {
+	Name    string `json:"name"`
+	Votes   int64  `json:"votes"`
+	AddedBy int64  `json:"added_by,omitempty"`
+	Voted   bool   `json:"voted"`
+}
+
+type PopularTag struct {
+	Name   string `json:"name"`
+	Movies int64  `json:"movies"`
+	Votes  int64  `json:"votes"`
+}
+
+// refreshMovieTagsQuery rebuilds the denormalized movies.tags column, which
+// backs the GIN index used by the ?tags= listing filter, most voted first.
+const refreshMovieTagsQuery = `
+	UPDATE movies
+	SET tags = ARRAY(
+		SELECT tags.name
+		FROM movie_tags
+		INNER JOIN tags ON tags.id = movie_tags.tag_id
+		WHERE movie_tags.movie_id = movies.id
+		GROUP BY tags.name
+		ORDER BY count(*) DESC, tags.name
+	)
+	WHERE id = $1`
+
+// lockMovieTagsQuery serializes tag votes on a movie, so that each refresh of
+// movies.tags sees the votes committed before it.
+const lockMovieTagsQuery = `SELECT 1 FROM movies WHERE id = $1 FOR UPDATE`
+
+type TagModel struct {
+	DB *sql.DB
+}
+
+// Vote records the user's vote for each tag on the movie, creating tags that
+// do not exist yet. Voting twice for the same tag has no effect.
+func (m TagModel) Vote(movieID, userID int64, tags []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, lockMovieTagsQuery, movieID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO tags (name)
+		SELECT unnest($1::text[])
+		ON CONFLICT (name) DO NOTHING`
+
+	_, err = tx.ExecContext(ctx, query, pq.Array(tags))
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO movie_tags (movie_id, tag_id, user_id)
+		SELECT $1, id, $2
+		FROM tags
+		WHERE name = ANY($3)
+		ON CONFLICT DO NOTHING`
+
+	_, err = tx.ExecContext(ctx, query, movieID, userID, pq.Array(tags))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, refreshMovieTagsQuery, movieID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Unvote withdraws the user's vote for the tag on the movie. A tag without
+// votes disappears from the movie.
+func (m TagModel) Unvote(movieID, userID int64, tag string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, lockMovieTagsQuery, movieID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		DELETE FROM movie_tags
+		USING tags
+		WHERE tags.id = movie_tags.tag_id
+		AND movie_tags.movie_id = $1
+		AND movie_tags.user_id = $2
+		AND tags.name = $3`
+
+	result, err := tx.ExecContext(ctx, query, movieID, userID, tag)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, refreshMovieTagsQuery, movieID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAllForMovie lists the movie's tags with their vote counts, the user who
+// added each tag first and whether viewerID has voted for it.
+func (m TagModel) GetAllForMovie(movieID, viewerID int64) ([]*MovieTag, error) {
+	query := `
+		SELECT tags.name, count(*),
+			(array_agg(movie_tags.user_id ORDER BY movie_tags.created_at, movie_tags.user_id))[1],
+			bool_or(movie_tags.user_id = $2)
+		FROM movie_tags
+		INNER JOIN tags ON tags.id = movie_tags.tag_id
+		WHERE movie_tags.movie_id = $1
+		GROUP BY tags.name
+		ORDER BY count(*) DESC, tags.name`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*MovieTag{}
+
+	for rows.Next() {
+		var tag MovieTag
+
+		err := rows.Scan(&tag.Name, &tag.Votes, &tag.AddedBy, &tag.Voted)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, &tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// Popular returns the tags used on the most published movies, for a tag
+// cloud.
+func (m TagModel) Popular(limit int) ([]*PopularTag, error) {
+	query := `
+		SELECT tags.name, count(DISTINCT movie_tags.movie_id), count(*)
+		FROM movie_tags
+		INNER JOIN tags ON tags.id = movie_tags.tag_id
+		INNER JOIN movies ON movies.id = movie_tags.movie_id
+		WHERE movies.status = 'published'
+		GROUP BY tags.name
+		ORDER BY count(DISTINCT movie_tags.movie_id) DESC, count(*) DESC, tags.name
+		LIMIT $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*PopularTag{}
+
+	for rows.Next() {
+		var tag PopularTag
+
+		err := rows.Scan(&tag.Name, &tag.Movies, &tag.Votes)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, &tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
@@ -6,14 +6,19 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/base32"
+	"errors"
 	"time"
 
 	"github.com/SemmiDev/chimovies/internal/validator"
+	"github.com/lib/pq"
 )
 
 const (
 	ScopeActivation     = "activation"
 	ScopeAuthentication = "authentication"
+	ScopePasswordReset  = "password-reset"
+	ScopeRefresh        = "refresh"
+	ScopeMFAPending     = "mfa-pending"
 )
 
 type Token struct {
@@ -22,6 +27,14 @@ type Token struct {
 	UserID    int64     `json:"-"`
 	Expiry    time.Time `json:"expiry"`
 	Scope     string    `json:"-"`
+	IP        string    `json:"-"`
+	UserAgent string    `json:"-"`
+	Family    []byte    `json:"-"`
+
+	// Permissions narrows what the token may do to a subset of the user's
+	// permissions; tokens issued to OAuth clients carry the client's.
+	Permissions Permissions `json:"-"`
+	ClientID    string      `json:"-"`
 }
 
 func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error) {
@@ -38,12 +51,17 @@ func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error
 	}
 
 	token.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
-	hash := sha256.Sum256([]byte(token.Plaintext))
-	token.Hash = hash[:]
+	token.Hash = HashToken(token.Plaintext)
 
 	return token, nil
 }
 
+// HashToken returns the SHA-256 hash under which a token is stored.
+func HashToken(tokenPlaintext string) []byte {
+	hash := sha256.Sum256([]byte(tokenPlaintext))
+	return hash[:]
+}
+
 func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
 	v.Check(tokenPlaintext != "", "token", "must be provided")
 	v.Check(len(tokenPlaintext) == 26, "token", "must be 26 bytes long")
@@ -63,17 +81,34 @@ func (m *TokenModel) New(userID int64, ttl time.Duration, scope string) (*Token,
 	return token, err
 }
 
-func (m *TokenModel) Insert(token *Token) error {
-	query := `
-	    INSERT INTO tokens (hash, user_id, expiry, scope)
-	    VALUES($1,$2,$3,$4)`
+const insertTokenQuery = `
+	INSERT INTO tokens (hash, user_id, expiry, scope, ip, user_agent, family, permissions, client_id)
+	VALUES($1,$2,$3,$4,$5,$6,$7,$8,NULLIF($9, ''))`
+
+func (t *Token) insertArgs() []interface{} {
+	return []interface{}{t.Hash, t.UserID, t.Expiry, t.Scope, t.IP, t.UserAgent, t.Family, pq.Array([]string(t.Permissions)), t.ClientID}
+}
+
+// NewForClient issues an authentication token to an OAuth client, limited to
+// the given permissions.
+func (m *TokenModel) NewForClient(client *OAuthClient, ttl time.Duration, permissions Permissions) (*Token, error) {
+	token, err := generateToken(client.UserID, ttl, ScopeAuthentication)
+	if err != nil {
+		return nil, err
+	}
 
-	args := []interface{}{token.Hash, token.UserID, token.Expiry, token.Scope}
+	token.Permissions = permissions
+	token.ClientID = client.ID
 
+	err = m.Insert(token)
+	return token, err
+}
+
+func (m *TokenModel) Insert(token *Token) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, args...)
+	_, err := m.DB.ExecContext(ctx, insertTokenQuery, token.insertArgs()...)
 	return err
 }
 
@@ -88,3 +123,144 @@ func (m *TokenModel) DeleteAllForUser(scope string, userID int64) error {
 	_, err := m.DB.ExecContext(ctx, query, scope, userID)
 	return err
 }
+
+// DeleteByHash deletes a token together with the rest of its family, so that
+// signing out with an access token also revokes its refresh token.
+func (m *TokenModel) DeleteByHash(hash []byte) error {
+	query := `
+	    DELETE FROM tokens
+	    WHERE hash = $1
+	    OR family = (SELECT family FROM tokens WHERE hash = $1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, hash)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *TokenModel) DeleteFamily(family []byte) error {
+	query := `
+	    DELETE FROM tokens
+	    WHERE family = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, family)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// RecordFailedAttempt counts a failed use of the token and deletes it once
+// the limit is reached, reporting whether it was deleted.
+func (m *TokenModel) RecordFailedAttempt(hash []byte, limit int) (bool, error) {
+	query := `
+	    UPDATE tokens
+	    SET attempts = attempts + 1
+	    WHERE hash = $1
+	    RETURNING attempts`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var attempts int
+
+	err := m.DB.QueryRowContext(ctx, query, hash).Scan(&attempts)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return true, nil
+		default:
+			return false, err
+		}
+	}
+
+	if attempts < limit {
+		return false, nil
+	}
+
+	_, err = m.DB.ExecContext(ctx, `DELETE FROM tokens WHERE hash = $1`, hash)
+	return err == nil, err
+}
+
+// Reissue replaces the user's tokens of the given scope with a new one, unless
+// one was issued less than cooldown ago, in which case it returns how long is
+// left instead. The user row is locked so that concurrent requests cannot all
+// pass the cooldown.
+func (m *TokenModel) Reissue(userID int64, ttl time.Duration, scope string, cooldown time.Duration) (*Token, time.Duration, error) {
+	token, err := generateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+	    SELECT created_at, NOW()
+	    FROM tokens
+	    WHERE scope = $1 AND user_id = $2
+	    ORDER BY created_at DESC
+	    LIMIT 1`
+
+	var createdAt, now time.Time
+
+	err = tx.QueryRowContext(ctx, query, scope, userID).Scan(&createdAt, &now)
+	switch {
+	case err == nil:
+		if wait := cooldown - now.Sub(createdAt); wait > 0 {
+			return nil, wait, nil
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM tokens WHERE scope = $1 AND user_id = $2`, scope, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, insertTokenQuery, token.insertArgs()...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return token, 0, tx.Commit()
+}
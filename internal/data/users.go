@@ -2,12 +2,12 @@ package data
 
 import (
 	"context"
-	"crypto/sha256"
 	"database/sql"
 	"errors"
 	"time"
 
 	"github.com/SemmiDev/chimovies/internal/validator"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -25,12 +25,30 @@ type User struct {
 	Activated bool      `json:"activated"`
 	Version   int       `json:"-"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// Permissions is set when the request carried its own permissions, as
+	// signed access tokens do. A nil value means they must be looked up.
+	Permissions Permissions `json:"-"`
+
+	// ClientID is set when the request was made with a token issued to an
+	// OAuth client acting as the user.
+	ClientID string `json:"-"`
 }
 
 func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 }
 
+// dummyPasswordHash has the same cost as real hashes, so that checking a
+// password for an unknown email takes as long as for a known one.
+var dummyPasswordHash = []byte("$2a$12$S4eBUoyQkRjEkS22lHsiwuJZT0x1k0aFo35e1pSO5duFOsfMZLLsO")
+
+// DummyPasswordCheck spends the time of a password comparison without
+// checking anything, for sign in attempts with an unknown email.
+func DummyPasswordCheck(plaintextPassword string) {
+	_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(plaintextPassword))
+}
+
 type password struct {
 	plaintext *string
 	hash      []byte
@@ -114,6 +132,38 @@ func (m UserModel) Insert(user *User) error {
 	return nil
 }
 
+func (m UserModel) Get(id int64) (*User, error) {
+	query := `
+		SELECT id, created_at, name, email, password_hash, activated, version
+		FROM users
+		WHERE id = $1`
+
+	var user User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Password.hash,
+		&user.Activated,
+		&user.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
 func (m UserModel) GetByEmail(email string) (*User, error) {
 	query := `
 		SELECT id, created_at, name, email, password_hash, activated, version
@@ -180,10 +230,11 @@ func (m UserModel) Update(user *User) error {
 }
 
 func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
-	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+	tokenHash := HashToken(tokenPlaintext)
 
 	query := `
-		SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version
+		SELECT users.id, users.created_at, users.name, users.email, users.password_hash, users.activated, users.version, tokens.permissions,
+			COALESCE(tokens.client_id, '')
 		FROM users
 		INNER JOIN tokens
 		ON users.id = tokens.user_id
@@ -191,7 +242,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		AND tokens.scope = $2
 		AND tokens.expiry > $3`
 
-	args := []interface{}{tokenHash[:], tokenScope, time.Now()}
+	args := []interface{}{tokenHash, tokenScope, time.Now()}
 
 	var user User
 
@@ -206,6 +257,8 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		&user.Password.hash,
 		&user.Activated,
 		&user.Version,
+		pq.Array((*[]string)(&user.Permissions)),
+		&user.ClientID,
 	)
 
 	if err != nil {
@@ -0,0 +1,205 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/SemmiDev/chimovies/internal/validator"
+	"github.com/SemmiDev/chimovies/internal/videos"
+)
+
+const (
+	VideoTrailer = "trailer"
+	VideoClip    = "clip"
+)
+
+type Video struct {
+	ID        int64         `json:"id"`
+	MovieID   int64         `json:"movie_id"`
+	Kind      string        `json:"kind"`
+	Provider  string        `json:"provider"`
+	URL       string        `json:"url"`
+	Language  string        `json:"language"`
+	Duration  int32         `json:"duration"`
+	Official  bool          `json:"official"`
+	Health    videos.Health `json:"health"`
+	Version   int32         `json:"version"`
+	CreatedAt time.Time     `json:"-"`
+}
+
+func ValidateVideo(v *validator.Validator, video *Video) {
+	v.Check(validator.In(video.Kind, VideoTrailer, VideoClip), "kind", "must be trailer or clip")
+	v.Check(validator.In(video.Provider, videos.Providers...), "provider", "must be one of youtube, vimeo or dailymotion")
+	v.Check(video.URL != "", "url", "must be provided")
+	v.Check(len(video.URL) <= 2000, "url", "must not be more than 2000 bytes long")
+
+	ValidateLanguage(v, video.Language)
+
+	v.Check(video.Duration > 0, "duration", "must be a positive number of seconds")
+	v.Check(video.Duration <= 4*60*60, "duration", "must not be longer than 4 hours")
+}
+
+type VideoModel struct {
+	DB *sql.DB
+}
+
+func (m *VideoModel) Insert(video *Video) error {
+	query := `
+		INSERT INTO videos (movie_id, kind, provider, url, language, duration, official)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, version, health_status`
+
+	args := []interface{}{video.MovieID, video.Kind, video.Provider, video.URL, video.Language, video.Duration, video.Official}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&video.ID, &video.CreatedAt, &video.Version, &video.Health.Status)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err, "videos_movie_id_url_key"):
+			return ErrDuplicateName
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *VideoModel) Get(id int64) (*Video, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, created_at, movie_id, kind, provider, url, language, duration, official,
+			health_status, COALESCE(health_code, 0), health_error, health_checked_at, version
+		FROM videos
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	video, err := scanVideo(m.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return video, nil
+}
+
+func (m *VideoModel) GetAllForMovie(movieID int64) ([]*Video, error) {
+	query := `
+		SELECT id, created_at, movie_id, kind, provider, url, language, duration, official,
+			health_status, COALESCE(health_code, 0), health_error, health_checked_at, version
+		FROM videos
+		WHERE movie_id = $1
+		ORDER BY official DESC, kind DESC, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*Video{}
+
+	for rows.Next() {
+		video, err := scanVideo(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, video)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (m *VideoModel) UpdateHealth(video *Video) error {
+	query := `
+		UPDATE videos
+		SET health_status = $2, health_code = NULLIF($3, 0), health_error = $4, health_checked_at = $5
+		WHERE id = $1`
+
+	args := []interface{}{video.ID, video.Health.Status, video.Health.StatusCode, video.Health.Error, video.Health.CheckedAt}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (m *VideoModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM videos WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func scanVideo(row rowScanner) (*Video, error) {
+	var (
+		video     Video
+		checkedAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&video.ID,
+		&video.CreatedAt,
+		&video.MovieID,
+		&video.Kind,
+		&video.Provider,
+		&video.URL,
+		&video.Language,
+		&video.Duration,
+		&video.Official,
+		&video.Health.Status,
+		&video.Health.StatusCode,
+		&video.Health.Error,
+		&checkedAt,
+		&video.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkedAt.Valid {
+		video.Health.CheckedAt = &checkedAt.Time
+	}
+
+	return &video, nil
+}
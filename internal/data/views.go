@@ -0,0 +1,129 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type ViewCount struct {
+	Views int64
+	Hits  int64
+}
+
+type TrendingMovie struct {
+	Movie      *Movie `json:"movie"`
+	Views      int64  `json:"views"`
+	SearchHits int64  `json:"search_hits"`
+}
+
+type ViewModel struct {
+	DB *sql.DB
+}
+
+func (m ViewModel) Add(bucket time.Time, counts map[int64]*ViewCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO movie_view_buckets (movie_id, bucket, views, hits)
+		SELECT i.movie_id, $1, i.views, i.hits
+		FROM unnest($2::bigint[], $3::bigint[], $4::bigint[]) AS i(movie_id, views, hits)
+		INNER JOIN movies ON movies.id = i.movie_id
+		ON CONFLICT (movie_id, bucket) DO UPDATE
+		SET views = movie_view_buckets.views + EXCLUDED.views, hits = movie_view_buckets.hits + EXCLUDED.hits`
+
+	var (
+		ids   = make([]int64, 0, len(counts))
+		views = make([]int64, 0, len(counts))
+		hits  = make([]int64, 0, len(counts))
+	)
+
+	for id, count := range counts {
+		ids = append(ids, id)
+		views = append(views, count.Views)
+		hits = append(hits, count.Hits)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, bucket, pq.Array(ids), pq.Array(views), pq.Array(hits))
+	return err
+}
+
+func (m ViewModel) DeleteBefore(bucket time.Time) error {
+	query := `
+		DELETE FROM movie_view_buckets
+		WHERE bucket < $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, bucket)
+	return err
+}
+
+func (m ViewModel) Trending(since time.Time, filters Filters) ([]*TrendingMovie, Metadata, error) {
+	query := `
+		SELECT count(*) OVER(), movies.id, movies.created_at, movies.title, movies.year, movies.runtime, movies.genres,
+			movies.status, movies.version, t.views, t.hits
+		FROM (
+			SELECT movie_id, sum(views) AS views, sum(hits) AS hits
+			FROM movie_view_buckets
+			WHERE bucket >= $1
+			GROUP BY movie_id
+		) AS t
+		INNER JOIN movies ON movies.id = t.movie_id
+		WHERE movies.status = 'published'
+		ORDER BY t.views + t.hits DESC, movies.id ASC
+		LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, since, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	trending := []*TrendingMovie{}
+
+	for rows.Next() {
+		var movie Movie
+		var t TrendingMovie
+
+		err := rows.Scan(
+			&totalRecords,
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Status,
+			&movie.Version,
+			&t.Views,
+			&t.SearchHits,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		t.Movie = &movie
+		trending = append(trending, &t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return trending, metadata, nil
+}
@@ -0,0 +1,191 @@
+package imdb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const nullValue = `\N`
+
+var ErrMalformedRow = errors.New("malformed row")
+
+type Title struct {
+	TConst  string
+	Title   string
+	Year    int32
+	Runtime int32
+	Genres  []string
+}
+
+type Rating struct {
+	Average float64
+	Votes   int64
+}
+
+type tsvReader struct {
+	file    *os.File
+	gz      *gzip.Reader
+	scanner *bufio.Scanner
+	line    int
+}
+
+func openTSV(path string) (*tsvReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	r := &tsvReader{file: file, gz: gz, scanner: scanner}
+
+	// The first line of every dataset is the column header.
+	if _, err := r.next(); err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *tsvReader) next() ([]string, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	r.line++
+	return strings.Split(r.scanner.Text(), "\t"), nil
+}
+
+func (r *tsvReader) Close() error {
+	r.gz.Close()
+	return r.file.Close()
+}
+
+func ReadRatings(path string) (map[string]Rating, error) {
+	r, err := openTSV(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	ratings := make(map[string]Rating)
+
+	for {
+		fields, err := r.next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return ratings, nil
+			}
+			return nil, err
+		}
+
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s line %d: %w", path, r.line, ErrMalformedRow)
+		}
+
+		average, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid averageRating %q", path, r.line, fields[1])
+		}
+
+		votes, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: invalid numVotes %q", path, r.line, fields[2])
+		}
+
+		ratings[fields[0]] = Rating{Average: average, Votes: votes}
+	}
+}
+
+type BasicsReader struct {
+	tsv  *tsvReader
+	path string
+}
+
+func OpenBasics(path string) (*BasicsReader, error) {
+	tsv, err := openTSV(path)
+	if err != nil {
+		return nil, err
+	}
+	return &BasicsReader{tsv: tsv, path: path}, nil
+}
+
+// Line reports the number of data rows consumed so far, not counting the header.
+func (r *BasicsReader) Line() int {
+	return r.tsv.line - 1
+}
+
+func (r *BasicsReader) Skip(lines int) error {
+	for r.Line() < lines {
+		if _, err := r.tsv.next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Next returns the next row whose titleType is "movie". Rows of any other
+// type are consumed silently. It returns io.EOF once the file is exhausted.
+func (r *BasicsReader) Next() (*Title, error) {
+	for {
+		fields, err := r.tsv.next()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(fields) != 9 {
+			return nil, fmt.Errorf("%s line %d: %w", r.path, r.tsv.line, ErrMalformedRow)
+		}
+
+		if fields[1] != "movie" {
+			continue
+		}
+
+		title := &Title{
+			TConst: fields[0],
+			Title:  fields[2],
+		}
+
+		if fields[5] != nullValue {
+			year, err := strconv.ParseInt(fields[5], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%s line %d: invalid startYear %q", r.path, r.tsv.line, fields[5])
+			}
+			title.Year = int32(year)
+		}
+
+		if fields[7] != nullValue {
+			runtime, err := strconv.ParseInt(fields[7], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%s line %d: invalid runtimeMinutes %q", r.path, r.tsv.line, fields[7])
+			}
+			title.Runtime = int32(runtime)
+		}
+
+		if fields[8] != nullValue {
+			title.Genres = strings.Split(fields[8], ",")
+		}
+
+		return title, nil
+	}
+}
+
+func (r *BasicsReader) Close() error {
+	return r.tsv.Close()
+}
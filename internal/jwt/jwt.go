@@ -0,0 +1,323 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AlgEdDSA = "EdDSA"
+	AlgRS256 = "RS256"
+)
+
+// leeway tolerates small clock differences between the services that issue
+// and verify tokens.
+const leeway = 30 * time.Second
+
+var (
+	ErrMalformed        = errors.New("jwt: malformed token")
+	ErrUnknownKey       = errors.New("jwt: unknown signing key")
+	ErrInvalidSignature = errors.New("jwt: invalid signature")
+	ErrExpired          = errors.New("jwt: token has expired")
+	ErrInvalidIssuer    = errors.New("jwt: invalid issuer")
+)
+
+var encoding = base64.RawURLEncoding
+
+type Claims struct {
+	Issuer      string   `json:"iss"`
+	Subject     string   `json:"sub"`
+	IssuedAt    int64    `json:"iat"`
+	Expiry      int64    `json:"exp"`
+	Activated   bool     `json:"act"`
+	Permissions []string `json:"perms"`
+	Session     string   `json:"sid,omitempty"`
+	Client      string   `json:"cid,omitempty"`
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+type Key struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+	CreatedAt time.Time
+}
+
+func GenerateKey(algorithm string) (*Key, error) {
+	var (
+		private crypto.Signer
+		err     error
+	)
+
+	switch algorithm {
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return nil, fmt.Errorf("jwt: unsupported algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 12)
+	_, err = rand.Read(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{ID: encoding.EncodeToString(id), Algorithm: algorithm, Private: private, CreatedAt: time.Now()}, nil
+}
+
+// MarshalPrivateKey encodes the private key as PKCS #8 DER for storage.
+func (k *Key) MarshalPrivateKey() ([]byte, error) {
+	return x509.MarshalPKCS8PrivateKey(k.Private)
+}
+
+func ParseKey(id, algorithm string, der []byte, createdAt time.Time) (*Key, error) {
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	var private crypto.Signer
+
+	switch key := parsed.(type) {
+	case ed25519.PrivateKey:
+		if algorithm != AlgEdDSA {
+			return nil, fmt.Errorf("jwt: key %s is not an %s key", id, algorithm)
+		}
+		private = key
+	case *rsa.PrivateKey:
+		if algorithm != AlgRS256 {
+			return nil, fmt.Errorf("jwt: key %s is not an %s key", id, algorithm)
+		}
+		private = key
+	default:
+		return nil, fmt.Errorf("jwt: key %s has an unsupported type", id)
+	}
+
+	return &Key{ID: id, Algorithm: algorithm, Private: private, CreatedAt: createdAt}, nil
+}
+
+func (k *Key) sign(input []byte) ([]byte, error) {
+	switch k.Algorithm {
+	case AlgEdDSA:
+		return k.Private.Sign(rand.Reader, input, crypto.Hash(0))
+	case AlgRS256:
+		digest := sha256.Sum256(input)
+		return k.Private.Sign(rand.Reader, digest[:], crypto.SHA256)
+	default:
+		return nil, fmt.Errorf("jwt: unsupported algorithm %q", k.Algorithm)
+	}
+}
+
+func (k *Key) verify(input, signature []byte) bool {
+	switch public := k.Private.Public().(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(public, input, signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(input)
+		return rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return false
+	}
+}
+
+// JWK is the public half of a key as published in a JWKS document.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+func (k *Key) JWK() JWK {
+	jwk := JWK{KeyID: k.ID, Algorithm: k.Algorithm, Use: "sig"}
+
+	switch public := k.Private.Public().(type) {
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encoding.EncodeToString(public)
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encoding.EncodeToString(public.N.Bytes())
+		jwk.E = encoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	}
+
+	return jwk
+}
+
+// KeySet holds the keys that tokens may be signed with. A new key is only
+// used for signing once it is older than the activation delay, so that every
+// verifier has had the chance to fetch it first; until then the newest older
+// key keeps signing. Older keys are kept so that tokens they signed still
+// verify.
+type KeySet struct {
+	mu         sync.RWMutex
+	keys       []*Key
+	activation time.Duration
+}
+
+func NewKeySet(activation time.Duration) *KeySet {
+	return &KeySet{activation: activation}
+}
+
+// Replace swaps in a new set of keys, which must be ordered newest first.
+func (s *KeySet) Replace(keys []*Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys = keys
+}
+
+// signingKey returns the newest key past the activation delay, or the newest
+// key when none is, as on first boot.
+func (s *KeySet) signingKey() *Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.keys) == 0 {
+		return nil
+	}
+
+	for _, key := range s.keys {
+		if time.Since(key.CreatedAt) >= s.activation {
+			return key
+		}
+	}
+	return s.keys[0]
+}
+
+func (s *KeySet) lookup(id string) *Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if key.ID == id {
+			return key
+		}
+	}
+	return nil
+}
+
+// PublicKeys returns the keys of the set for publishing in a JWKS document.
+func (s *KeySet) PublicKeys() []JWK {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]JWK, len(s.keys))
+	for i, key := range s.keys {
+		keys[i] = key.JWK()
+	}
+	return keys
+}
+
+func (s *KeySet) Sign(claims Claims) (string, error) {
+	key := s.signingKey()
+	if key == nil {
+		return "", ErrUnknownKey
+	}
+
+	h, err := json.Marshal(header{Algorithm: key.Algorithm, Type: "JWT", KeyID: key.ID})
+	if err != nil {
+		return "", err
+	}
+
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	input := encoding.EncodeToString(h) + "." + encoding.EncodeToString(c)
+
+	signature, err := key.sign([]byte(input))
+	if err != nil {
+		return "", err
+	}
+
+	return input + "." + encoding.EncodeToString(signature), nil
+}
+
+// Verify checks the token's signature against the key named in its header,
+// then its expiry and issuer. The algorithm must match the one the key was
+// created for, so a token cannot pick a weaker algorithm for itself.
+func (s *KeySet) Verify(token, issuer string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	h, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	var hdr header
+	if err := json.Unmarshal(h, &hdr); err != nil {
+		return nil, ErrMalformed
+	}
+
+	key := s.lookup(hdr.KeyID)
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	if hdr.Algorithm != key.Algorithm || !key.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	c, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(c, &claims); err != nil {
+		return nil, ErrMalformed
+	}
+
+	if time.Now().Add(-leeway).Unix() >= claims.Expiry {
+		return nil, ErrExpired
+	}
+
+	if claims.Issuer != issuer {
+		return nil, ErrInvalidIssuer
+	}
+
+	return &claims, nil
+}
+
+// LooksLikeJWT reports whether the token has the three dot-separated parts of
+// a compact JWT, as opposed to an opaque token.
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
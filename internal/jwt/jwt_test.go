@@ -0,0 +1,285 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testIssuer = "chimovies"
+
+func newTestKey(t *testing.T, algorithm string, age time.Duration) *Key {
+	t.Helper()
+
+	key, err := GenerateKey(algorithm)
+	if err != nil {
+		t.Fatalf("GenerateKey(%q): %v", algorithm, err)
+	}
+	key.CreatedAt = time.Now().Add(-age)
+
+	return key
+}
+
+func testClaims(expiry time.Time) Claims {
+	return Claims{
+		Issuer:      testIssuer,
+		Subject:     "42",
+		IssuedAt:    time.Now().Unix(),
+		Expiry:      expiry.Unix(),
+		Activated:   true,
+		Permissions: []string{"movies:read"},
+		Session:     "c2Vzc2lvbg",
+	}
+}
+
+// forge signs arbitrary header and claims with the key, bypassing KeySet.Sign.
+func forge(t *testing.T, key *Key, hdr header, claims Claims) string {
+	t.Helper()
+
+	h, _ := json.Marshal(hdr)
+	c, _ := json.Marshal(claims)
+	input := encoding.EncodeToString(h) + "." + encoding.EncodeToString(c)
+
+	signature, err := key.sign([]byte(input))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	return input + "." + encoding.EncodeToString(signature)
+}
+
+func TestSignVerify(t *testing.T) {
+	for _, algorithm := range []string{AlgEdDSA, AlgRS256} {
+		t.Run(algorithm, func(t *testing.T) {
+			keys := NewKeySet(0)
+			keys.Replace([]*Key{newTestKey(t, algorithm, 0)})
+
+			want := testClaims(time.Now().Add(time.Minute))
+
+			token, err := keys.Sign(want)
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+
+			if !LooksLikeJWT(token) {
+				t.Fatalf("%q does not look like a JWT", token)
+			}
+
+			got, err := keys.Verify(token, testIssuer)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+
+			if got.Subject != want.Subject || got.Expiry != want.Expiry || got.Session != want.Session ||
+				!got.Activated || len(got.Permissions) != 1 || got.Permissions[0] != "movies:read" {
+				t.Errorf("Verify = %+v; want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	keys := NewKeySet(0)
+	keys.Replace([]*Key{newTestKey(t, AlgEdDSA, 0)})
+
+	token, err := keys.Sign(testClaims(time.Now().Add(time.Minute)))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+
+	claims := testClaims(time.Now().Add(time.Minute))
+	claims.Permissions = []string{"movies:read", "movies:write"}
+	c, _ := json.Marshal(claims)
+
+	signature := []byte(parts[2])
+	signature[0] ^= 'A' ^ 'B'
+
+	tests := map[string]string{
+		"payload":   parts[0] + "." + encoding.EncodeToString(c) + "." + parts[2],
+		"signature": parts[0] + "." + parts[1] + "." + string(signature),
+		"truncated": parts[0] + "." + parts[1] + ".",
+	}
+
+	for name, tampered := range tests {
+		_, err := keys.Verify(tampered, testIssuer)
+		if !errors.Is(err, ErrInvalidSignature) && !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: error = %v; want ErrInvalidSignature or ErrMalformed", name, err)
+		}
+	}
+}
+
+func TestVerifyWrongAlgorithm(t *testing.T) {
+	key := newTestKey(t, AlgEdDSA, 0)
+
+	keys := NewKeySet(0)
+	keys.Replace([]*Key{key})
+
+	claims := testClaims(time.Now().Add(time.Minute))
+
+	for _, algorithm := range []string{AlgRS256, "none", "HS256", ""} {
+		token := forge(t, key, header{Algorithm: algorithm, Type: "JWT", KeyID: key.ID}, claims)
+
+		_, err := keys.Verify(token, testIssuer)
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("alg %q: error = %v; want ErrInvalidSignature", algorithm, err)
+		}
+	}
+
+	parts := strings.Split(forge(t, key, header{Algorithm: AlgEdDSA, Type: "JWT", KeyID: key.ID}, claims), ".")
+	h, _ := json.Marshal(header{Algorithm: "none", Type: "JWT", KeyID: key.ID})
+
+	_, err := keys.Verify(encoding.EncodeToString(h)+"."+parts[1]+".", testIssuer)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("unsigned token: error = %v; want ErrInvalidSignature", err)
+	}
+}
+
+func TestVerifyUnknownKey(t *testing.T) {
+	signer := NewKeySet(0)
+	signer.Replace([]*Key{newTestKey(t, AlgEdDSA, 0)})
+
+	verifier := NewKeySet(0)
+	verifier.Replace([]*Key{newTestKey(t, AlgEdDSA, 0)})
+
+	token, err := signer.Sign(testClaims(time.Now().Add(time.Minute)))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	_, err = verifier.Verify(token, testIssuer)
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("error = %v; want ErrUnknownKey", err)
+	}
+
+	_, err = NewKeySet(0).Sign(testClaims(time.Now()))
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Sign with no keys error = %v; want ErrUnknownKey", err)
+	}
+}
+
+func TestVerifyExpiry(t *testing.T) {
+	keys := NewKeySet(0)
+	keys.Replace([]*Key{newTestKey(t, AlgEdDSA, 0)})
+
+	tests := []struct {
+		expiry time.Time
+		want   error
+	}{
+		{time.Now().Add(-time.Minute), ErrExpired},
+		{time.Now().Add(-leeway - time.Second), ErrExpired},
+		{time.Now().Add(-leeway / 2), nil},
+		{time.Now().Add(time.Minute), nil},
+	}
+
+	for _, tt := range tests {
+		token, err := keys.Sign(testClaims(tt.expiry))
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+
+		_, err = keys.Verify(token, testIssuer)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("expiry %s ago: error = %v; want %v", time.Since(tt.expiry).Round(time.Second), err, tt.want)
+		}
+	}
+}
+
+func TestVerifyIssuer(t *testing.T) {
+	keys := NewKeySet(0)
+	keys.Replace([]*Key{newTestKey(t, AlgEdDSA, 0)})
+
+	token, err := keys.Sign(testClaims(time.Now().Add(time.Minute)))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	_, err = keys.Verify(token, "someone-else")
+	if !errors.Is(err, ErrInvalidIssuer) {
+		t.Errorf("error = %v; want ErrInvalidIssuer", err)
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	keys := NewKeySet(0)
+	keys.Replace([]*Key{newTestKey(t, AlgEdDSA, 0)})
+
+	for _, token := range []string{"", "abc", "a.b", "a.b.c.d", "!!!.b.c", encoding.EncodeToString([]byte("{")) + ".b.c"} {
+		_, err := keys.Verify(token, testIssuer)
+		if !errors.Is(err, ErrMalformed) {
+			t.Errorf("Verify(%q) error = %v; want ErrMalformed", token, err)
+		}
+	}
+}
+
+func TestKeySetActivation(t *testing.T) {
+	activation := 6 * time.Minute
+
+	old := newTestKey(t, AlgEdDSA, 30*24*time.Hour)
+	fresh := newTestKey(t, AlgEdDSA, time.Minute)
+
+	keys := NewKeySet(activation)
+	keys.Replace([]*Key{fresh, old})
+
+	if key := keys.signingKey(); key != old {
+		t.Errorf("signing with a key created %s ago; want the older key", time.Since(key.CreatedAt).Round(time.Minute))
+	}
+
+	// The new key is published and verifies tokens before it signs any.
+	published := keys.PublicKeys()
+	if len(published) != 2 || published[0].KeyID != fresh.ID {
+		t.Errorf("PublicKeys = %+v; want both keys, newest first", published)
+	}
+
+	token := forge(t, fresh, header{Algorithm: AlgEdDSA, Type: "JWT", KeyID: fresh.ID}, testClaims(time.Now().Add(time.Minute)))
+	if _, err := keys.Verify(token, testIssuer); err != nil {
+		t.Errorf("Verify with the new key: %v", err)
+	}
+
+	fresh.CreatedAt = time.Now().Add(-activation)
+	if key := keys.signingKey(); key != fresh {
+		t.Error("the new key does not sign once it is past the activation delay")
+	}
+
+	// On first boot there is no older key to fall back on.
+	first := NewKeySet(activation)
+	only := newTestKey(t, AlgEdDSA, 0)
+	first.Replace([]*Key{only})
+
+	if key := first.signingKey(); key != only {
+		t.Error("the only key does not sign on first boot")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	for _, algorithm := range []string{AlgEdDSA, AlgRS256} {
+		key := newTestKey(t, algorithm, 0)
+
+		der, err := key.MarshalPrivateKey()
+		if err != nil {
+			t.Fatalf("MarshalPrivateKey: %v", err)
+		}
+
+		parsed, err := ParseKey(key.ID, algorithm, der, key.CreatedAt)
+		if err != nil {
+			t.Fatalf("ParseKey: %v", err)
+		}
+
+		if parsed.JWK() != key.JWK() {
+			t.Errorf("%s: parsed JWK %+v; want %+v", algorithm, parsed.JWK(), key.JWK())
+		}
+
+		other := AlgRS256
+		if algorithm == AlgRS256 {
+			other = AlgEdDSA
+		}
+
+		_, err = ParseKey(key.ID, other, der, key.CreatedAt)
+		if err == nil {
+			t.Errorf("%s key parsed as %s", algorithm, other)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package subtitles
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatSRT    = "srt"
+	FormatWebVTT = "vtt"
+)
+
+var (
+	srtTimestampRX = regexp.MustCompile(`^(\d{2,}):(\d{2}):(\d{2}),(\d{3})$`)
+	vttTimestampRX = regexp.MustCompile(`^(?:(\d{2,}):)?(\d{2}):(\d{2})\.(\d{3})$`)
+)
+
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+type LineError struct {
+	Line    int
+	Message string
+}
+
+// ParseError lists every malformed cue found in a file, so that a user can
+// fix them all at once instead of one upload at a time.
+type ParseError struct {
+	Errors []LineError
+}
+
+func (e *ParseError) Error() string {
+	if len(e.Errors) == 0 {
+		return "malformed subtitle file"
+	}
+	first := e.Errors[0]
+	if len(e.Errors) == 1 {
+		return fmt.Sprintf("line %d: %s", first.Line, first.Message)
+	}
+	return fmt.Sprintf("line %d: %s (and %d more errors)", first.Line, first.Message, len(e.Errors)-1)
+}
+
+func (e *ParseError) add(line int, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, LineError{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+type line struct {
+	number int
+	text   string
+}
+
+// DetectFormat reports FormatWebVTT when the file starts with the WEBVTT
+// signature and FormatSRT otherwise.
+func DetectFormat(data []byte) string {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if bytes.HasPrefix(data, []byte("WEBVTT")) {
+		return FormatWebVTT
+	}
+	return FormatSRT
+}
+
+func Parse(data []byte) ([]Cue, error) {
+	errs := &ParseError{}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		errs.add(1, "file is empty")
+		return nil, errs
+	}
+
+	blocks := splitBlocks(data)
+
+	var cues []Cue
+
+	switch DetectFormat(data) {
+	case FormatWebVTT:
+		header := blocks[0]
+		signature := header[0].text
+		if signature != "WEBVTT" && !strings.HasPrefix(signature, "WEBVTT ") && !strings.HasPrefix(signature, "WEBVTT\t") {
+			errs.add(header[0].number, "invalid WEBVTT signature")
+		}
+		cues = parseBlocks(blocks[1:], vttTimestampRX, true, errs)
+	default:
+		cues = parseBlocks(blocks, srtTimestampRX, false, errs)
+	}
+
+	if len(errs.Errors) > 0 {
+		return nil, errs
+	}
+
+	if len(cues) == 0 {
+		errs.add(1, "file does not contain any cues")
+		return nil, errs
+	}
+
+	return cues, nil
+}
+
+func splitBlocks(data []byte) [][]line {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var (
+		blocks  [][]line
+		current []line
+	)
+
+	for i, raw := range strings.Split(text, "\n") {
+		if strings.TrimSpace(raw) == "" {
+			if len(current) > 0 {
+				blocks = append(blocks, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line{number: i + 1, text: strings.TrimRight(raw, " \t")})
+	}
+
+	if len(current) > 0 {
+		blocks = append(blocks, current)
+	}
+
+	return blocks
+}
+
+func parseBlocks(blocks [][]line, timestampRX *regexp.Regexp, webvtt bool, errs *ParseError) []Cue {
+	var cues []Cue
+
+	for _, block := range blocks {
+		if webvtt {
+			keyword := strings.Fields(block[0].text)[0]
+			if keyword == "NOTE" || keyword == "STYLE" || keyword == "REGION" {
+				continue
+			}
+		}
+
+		timing := 0
+		if !strings.Contains(block[0].text, "-->") {
+			// Both formats allow an identifier line before the timing line;
+			// SRT requires it to be the cue's sequence number.
+			if !webvtt {
+				if _, err := strconv.Atoi(strings.TrimSpace(block[0].text)); err != nil {
+					errs.add(block[0].number, "expected a cue number, got %q", block[0].text)
+					continue
+				}
+			}
+			timing = 1
+		}
+
+		if timing >= len(block) {
+			errs.add(block[0].number, "cue is missing its timing line")
+			continue
+		}
+
+		start, end, ok := parseTiming(block[timing], timestampRX, errs)
+		if !ok {
+			continue
+		}
+
+		text := make([]string, 0, len(block)-timing-1)
+		for _, l := range block[timing+1:] {
+			text = append(text, l.text)
+		}
+
+		if len(text) == 0 {
+			errs.add(block[timing].number, "cue has no text")
+			continue
+		}
+
+		cues = append(cues, Cue{Start: start, End: end, Text: strings.Join(text, "\n")})
+	}
+
+	return cues
+}
+
+func parseTiming(l line, timestampRX *regexp.Regexp, errs *ParseError) (time.Duration, time.Duration, bool) {
+	parts := strings.SplitN(l.text, "-->", 2)
+	if len(parts) != 2 {
+		errs.add(l.number, "expected a timing line like 00:00:01,000 --> 00:00:04,000, got %q", l.text)
+		return 0, 0, false
+	}
+
+	// WebVTT allows cue settings after the end timestamp; they are dropped.
+	endFields := strings.Fields(parts[1])
+	if len(endFields) == 0 {
+		errs.add(l.number, "missing end timestamp")
+		return 0, 0, false
+	}
+
+	start, err := parseTimestamp(strings.TrimSpace(parts[0]), timestampRX)
+	if err != nil {
+		errs.add(l.number, "invalid start timestamp %q", strings.TrimSpace(parts[0]))
+		return 0, 0, false
+	}
+
+	end, err := parseTimestamp(endFields[0], timestampRX)
+	if err != nil {
+		errs.add(l.number, "invalid end timestamp %q", endFields[0])
+		return 0, 0, false
+	}
+
+	if end <= start {
+		errs.add(l.number, "end timestamp must be after start timestamp")
+		return 0, 0, false
+	}
+
+	return start, end, true
+}
+
+func parseTimestamp(value string, timestampRX *regexp.Regexp) (time.Duration, error) {
+	matches := timestampRX.FindStringSubmatch(value)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	var hours int
+	if matches[1] != "" {
+		hours, _ = strconv.Atoi(matches[1])
+	}
+	minutes, _ := strconv.Atoi(matches[2])
+	seconds, _ := strconv.Atoi(matches[3])
+	millis, _ := strconv.Atoi(matches[4])
+
+	if minutes > 59 || seconds > 59 {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	return time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second +
+		time.Duration(millis)*time.Millisecond, nil
+}
+
+func formatTimestamp(d time.Duration) string {
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+	d -= minutes * time.Minute
+	seconds := d / time.Second
+	d -= seconds * time.Second
+
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", hours, minutes, seconds, d/time.Millisecond)
+}
+
+func WebVTT(cues []Cue) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString("WEBVTT\n")
+	for i, cue := range cues {
+		fmt.Fprintf(&buf, "\n%d\n%s --> %s\n%s\n", i+1, formatTimestamp(cue.Start), formatTimestamp(cue.End), cue.Text)
+	}
+
+	return buf.Bytes()
+}
+
+var markupRX = regexp.MustCompile(`<[^>]*>|\{\\[^}]*\}`)
+
+// PlainText joins the text of all cues with markup removed, for indexing.
+func PlainText(cues []Cue) string {
+	text := make([]string, len(cues))
+	for i, cue := range cues {
+		text[i] = markupRX.ReplaceAllString(strings.ReplaceAll(cue.Text, "\n", " "), "")
+	}
+	return strings.Join(text, "\n")
+}
@@ -0,0 +1,147 @@
+package subtitles
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseSRT(t *testing.T) {
+	input := "\ufeff1\r\n00:00:01,000 --> 00:00:04,500\r\nHello\r\nthere\r\n\r\n2\r\n01:02:03,004 --> 01:02:05,000\r\n<i>General Kenobi</i>\r\n"
+
+	cues, err := Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Cue{
+		{Start: time.Second, End: 4500 * time.Millisecond, Text: "Hello\nthere"},
+		{Start: time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond, End: time.Hour + 2*time.Minute + 5*time.Second, Text: "<i>General Kenobi</i>"},
+	}
+
+	if len(cues) != len(want) {
+		t.Fatalf("got %d cues; want %d", len(cues), len(want))
+	}
+
+	for i := range want {
+		if cues[i] != want[i] {
+			t.Errorf("cue %d = %+v; want %+v", i, cues[i], want[i])
+		}
+	}
+}
+
+func TestParseWebVTT(t *testing.T) {
+	input := "WEBVTT - Example\n\nNOTE a comment\nspanning lines\n\nSTYLE\n::cue { color: red }\n\nintro\n00:01.000 --> 00:04.000 align:start\nHello\n\n00:00:05.000 --> 00:00:06.000\nWorld\n"
+
+	cues, err := Parse([]byte(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []Cue{
+		{Start: time.Second, End: 4 * time.Second, Text: "Hello"},
+		{Start: 5 * time.Second, End: 6 * time.Second, Text: "World"},
+	}
+
+	if len(cues) != len(want) {
+		t.Fatalf("got %d cues; want %d", len(cues), len(want))
+	}
+
+	for i := range want {
+		if cues[i] != want[i] {
+			t.Errorf("cue %d = %+v; want %+v", i, cues[i], want[i])
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		lines []int
+	}{
+		{"empty", "  \n", []int{1}},
+		{"bad signature", "WEBVTTX\n\n00:01.000 --> 00:02.000\nHi\n", []int{1}},
+		{"no cues", "WEBVTT\n\nNOTE only a comment\n", []int{1}},
+		{"bad cue number", "one\n00:00:01,000 --> 00:00:02,000\nHi\n", []int{1}},
+		{"missing timing", "1\n\n2\n00:00:01,000 --> 00:00:02,000\nHi\n", []int{1}},
+		{"bad timestamp", "1\n00:00:01.000 --> 00:00:02,000\nHi\n", []int{2}},
+		{"minutes out of range", "1\n00:61:01,000 --> 00:62:02,000\nHi\n", []int{2}},
+		{"end before start", "1\n00:00:02,000 --> 00:00:01,000\nHi\n", []int{2}},
+		{"no text", "1\n00:00:01,000 --> 00:00:02,000\n", []int{2}},
+		{"several", "1\n00:00:02,000 --> 00:00:01,000\nHi\n\n2\n00:00:03,000 --> 00:00:04,000\n\n3\nnope\nHi\n", []int{2, 6, 9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input))
+
+			var parseError *ParseError
+			if !errors.As(err, &parseError) {
+				t.Fatalf("error = %v; want a *ParseError", err)
+			}
+
+			if len(parseError.Errors) != len(tt.lines) {
+				t.Fatalf("got errors %+v; want errors on lines %v", parseError.Errors, tt.lines)
+			}
+
+			for i, line := range tt.lines {
+				if parseError.Errors[i].Line != line {
+					t.Errorf("error %d on line %d; want line %d", i, parseError.Errors[i].Line, line)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]string{
+		"WEBVTT\n":        FormatWebVTT,
+		"\ufeffWEBVTT\n":  FormatWebVTT,
+		"1\n00:00:01,000": FormatSRT,
+		"NOTE WEBVTT\n":   FormatSRT,
+	}
+
+	for input, want := range tests {
+		if got := DetectFormat([]byte(input)); got != want {
+			t.Errorf("DetectFormat(%q) = %q; want %q", input, got, want)
+		}
+	}
+}
+
+func TestWebVTTRoundTrip(t *testing.T) {
+	cues := []Cue{
+		{Start: 1500 * time.Millisecond, End: 3 * time.Second, Text: "Hello\nthere"},
+		{Start: 25*time.Hour + 59*time.Minute, End: 25*time.Hour + 59*time.Minute + 59*time.Second + 999*time.Millisecond, Text: "Late"},
+	}
+
+	output := WebVTT(cues)
+
+	want := "WEBVTT\n\n1\n00:00:01.500 --> 00:00:03.000\nHello\nthere\n\n2\n25:59:00.000 --> 25:59:59.999\nLate\n"
+	if string(output) != want {
+		t.Fatalf("WebVTT =\n%s\nwant\n%s", output, want)
+	}
+
+	parsed, err := Parse(output)
+	if err != nil {
+		t.Fatalf("unexpected error parsing output: %v", err)
+	}
+
+	for i := range cues {
+		if parsed[i] != cues[i] {
+			t.Errorf("cue %d = %+v; want %+v", i, parsed[i], cues[i])
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	cues := []Cue{
+		{Text: "<i>Hello</i>\nthere"},
+		{Text: "{\\an8}<b>General</b> <c.yellow>Kenobi</c>"},
+	}
+
+	want := "Hello there\nGeneral Kenobi"
+	if got := PlainText(cues); got != want {
+		t.Errorf("PlainText = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,170 @@
+package videos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	ProviderYouTube     = "youtube"
+	ProviderVimeo       = "vimeo"
+	ProviderDailymotion = "dailymotion"
+)
+
+var Providers = []string{ProviderYouTube, ProviderVimeo, ProviderDailymotion}
+
+var (
+	ErrUnknownProvider = errors.New("unknown provider")
+	ErrInvalidURL      = errors.New("invalid video URL")
+
+	youtubeIDRX     = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	vimeoIDRX       = regexp.MustCompile(`^[0-9]+$`)
+	dailymotionIDRX = regexp.MustCompile(`^x[0-9a-z]+$`)
+)
+
+// EmbedURL checks rawURL against the URL shapes the provider is known to use
+// and returns the canonical embed URL for the video, so that the same video
+// pasted as a watch page, a short link or an embed is stored only once.
+func EmbedURL(provider, rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return "", fmt.Errorf("%w: must be an http or https URL", ErrInvalidURL)
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	switch provider {
+	case ProviderYouTube:
+		var id string
+		switch host {
+		case "youtu.be":
+			id = segments[0]
+		case "youtube.com", "m.youtube.com", "youtube-nocookie.com":
+			switch {
+			case len(segments) == 1 && segments[0] == "watch":
+				id = u.Query().Get("v")
+			case len(segments) == 2 && (segments[0] == "embed" || segments[0] == "shorts" || segments[0] == "v"):
+				id = segments[1]
+			}
+		default:
+			return "", fmt.Errorf("%w: not a YouTube URL", ErrInvalidURL)
+		}
+		if !youtubeIDRX.MatchString(id) {
+			return "", fmt.Errorf("%w: no YouTube video id found", ErrInvalidURL)
+		}
+		return "https://www.youtube.com/embed/" + id, nil
+
+	case ProviderVimeo:
+		var id string
+		switch host {
+		case "vimeo.com":
+			// vimeo.com/ID, vimeo.com/channels/NAME/ID and similar
+			// listing URLs all end with the numeric id.
+			id = segments[len(segments)-1]
+		case "player.vimeo.com":
+			if len(segments) == 2 && segments[0] == "video" {
+				id = segments[1]
+			}
+		default:
+			return "", fmt.Errorf("%w: not a Vimeo URL", ErrInvalidURL)
+		}
+		if !vimeoIDRX.MatchString(id) {
+			return "", fmt.Errorf("%w: no Vimeo video id found", ErrInvalidURL)
+		}
+		return "https://player.vimeo.com/video/" + id, nil
+
+	case ProviderDailymotion:
+		var id string
+		switch host {
+		case "dai.ly":
+			id = segments[0]
+		case "dailymotion.com":
+			switch {
+			case len(segments) == 2 && segments[0] == "video":
+				id = segments[1]
+			case len(segments) == 3 && segments[0] == "embed" && segments[1] == "video":
+				id = segments[2]
+			}
+		default:
+			return "", fmt.Errorf("%w: not a Dailymotion URL", ErrInvalidURL)
+		}
+		// Watch page slugs look like x8abc12-some-title.
+		id, _, _ = strings.Cut(id, "-")
+		if !dailymotionIDRX.MatchString(id) {
+			return "", fmt.Errorf("%w: no Dailymotion video id found", ErrInvalidURL)
+		}
+		return "https://www.dailymotion.com/embed/video/" + id, nil
+	}
+
+	return "", fmt.Errorf("%w %q", ErrUnknownProvider, provider)
+}
+
+const (
+	HealthUnknown = "unknown"
+	HealthOK      = "ok"
+	HealthBroken  = "broken"
+)
+
+type Health struct {
+	Status     string     `json:"status"`
+	StatusCode int        `json:"status_code,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CheckedAt  *time.Time `json:"checked_at,omitempty"`
+}
+
+// Checker probes video links with its own HTTP client so that timeouts,
+// proxies and, in tests, the target server can be configured by the caller.
+type Checker struct {
+	Client *http.Client
+}
+
+func NewChecker(client *http.Client) *Checker {
+	return &Checker{Client: client}
+}
+
+// Check requests rawURL and reports it healthy when it answers with a 2xx
+// status after redirects. Servers that do not support HEAD are retried with
+// GET.
+func (c *Checker) Check(ctx context.Context, rawURL string) Health {
+	now := time.Now().UTC()
+	health := Health{Status: HealthBroken, CheckedAt: &now}
+
+	status, err := c.do(ctx, http.MethodHead, rawURL)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = c.do(ctx, http.MethodGet, rawURL)
+	}
+
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+
+	health.StatusCode = status
+	if status >= 200 && status < 300 {
+		health.Status = HealthOK
+	}
+	return health
+}
+
+func (c *Checker) do(ctx context.Context, method, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "chimovies-link-checker/1.0")
+
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+
+	return res.StatusCode, nil
+}
@@ -0,0 +1,171 @@
+package videos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEmbedURL(t *testing.T) {
+	tests := []struct {
+		provider string
+		rawURL   string
+		want     string
+	}{
+		{ProviderYouTube, "https://youtu.be/dQw4w9WgXcQ", "https://www.youtube.com/embed/dQw4w9WgXcQ"},
+		{ProviderYouTube, "https://youtu.be/dQw4w9WgXcQ?t=42", "https://www.youtube.com/embed/dQw4w9WgXcQ"},
+		{ProviderYouTube, "https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PL1", "https://www.youtube.com/embed/dQw4w9WgXcQ"},
+		{ProviderYouTube, "http://m.youtube.com/watch?v=dQw4w9WgXcQ", "https://www.youtube.com/embed/dQw4w9WgXcQ"},
+		{ProviderYouTube, "https://youtube.com/shorts/dQw4w9WgXcQ", "https://www.youtube.com/embed/dQw4w9WgXcQ"},
+		{ProviderYouTube, "https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ", "https://www.youtube.com/embed/dQw4w9WgXcQ"},
+		{ProviderVimeo, "https://vimeo.com/76979871", "https://player.vimeo.com/video/76979871"},
+		{ProviderVimeo, "https://vimeo.com/channels/staffpicks/76979871", "https://player.vimeo.com/video/76979871"},
+		{ProviderVimeo, "https://player.vimeo.com/video/76979871", "https://player.vimeo.com/video/76979871"},
+		{ProviderDailymotion, "https://dai.ly/x8abc12", "https://www.dailymotion.com/embed/video/x8abc12"},
+		{ProviderDailymotion, "https://www.dailymotion.com/video/x8abc12-some-title", "https://www.dailymotion.com/embed/video/x8abc12"},
+		{ProviderDailymotion, "https://www.dailymotion.com/embed/video/x8abc12", "https://www.dailymotion.com/embed/video/x8abc12"},
+	}
+
+	for _, tt := range tests {
+		got, err := EmbedURL(tt.provider, tt.rawURL)
+		if err != nil {
+			t.Errorf("EmbedURL(%q, %q): unexpected error: %v", tt.provider, tt.rawURL, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("EmbedURL(%q, %q) = %q; want %q", tt.provider, tt.rawURL, got, tt.want)
+		}
+	}
+}
+
+func TestEmbedURLInvalid(t *testing.T) {
+	tests := []struct {
+		provider string
+		rawURL   string
+		want     error
+	}{
+		{ProviderYouTube, "ftp://youtu.be/dQw4w9WgXcQ", ErrInvalidURL},
+		{ProviderYouTube, "https://vimeo.com/76979871", ErrInvalidURL},
+		{ProviderYouTube, "https://youtu.be/short", ErrInvalidURL},
+		{ProviderYouTube, "https://www.youtube.com/watch", ErrInvalidURL},
+		{ProviderVimeo, "https://vimeo.com/channels/staffpicks", ErrInvalidURL},
+		{ProviderVimeo, "https://player.vimeo.com/76979871", ErrInvalidURL},
+		{ProviderDailymotion, "https://www.dailymotion.com/user/x8abc12", ErrInvalidURL},
+		{ProviderDailymotion, "https://dai.ly/X8ABC12", ErrInvalidURL},
+		{"twitch", "https://www.twitch.tv/videos/1", ErrUnknownProvider},
+	}
+
+	for _, tt := range tests {
+		_, err := EmbedURL(tt.provider, tt.rawURL)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("EmbedURL(%q, %q) error = %v; want %v", tt.provider, tt.rawURL, err, tt.want)
+		}
+	}
+}
+
+func TestCheckerCheck(t *testing.T) {
+	var methods []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	checker := NewChecker(server.Client())
+
+	tests := []struct {
+		path       string
+		status     string
+		statusCode int
+		methods    []string
+	}{
+		{"/ok", HealthOK, http.StatusOK, []string{"HEAD"}},
+		{"/missing", HealthBroken, http.StatusNotFound, []string{"HEAD"}},
+		{"/get-only", HealthOK, http.StatusOK, []string{"HEAD", "GET"}},
+		{"/moved", HealthOK, http.StatusOK, []string{"HEAD", "HEAD"}},
+	}
+
+	for _, tt := range tests {
+		methods = nil
+
+		health := checker.Check(context.Background(), server.URL+tt.path)
+
+		if health.Status != tt.status || health.StatusCode != tt.statusCode {
+			t.Errorf("%s: got %s (%d); want %s (%d)", tt.path, health.Status, health.StatusCode, tt.status, tt.statusCode)
+		}
+
+		if health.CheckedAt == nil {
+			t.Errorf("%s: CheckedAt is not set", tt.path)
+		}
+
+		if len(methods) != len(tt.methods) {
+			t.Errorf("%s: server saw %v; want %v", tt.path, methods, tt.methods)
+			continue
+		}
+		for i := range methods {
+			if methods[i] != tt.methods[i] {
+				t.Errorf("%s: server saw %v; want %v", tt.path, methods, tt.methods)
+				break
+			}
+		}
+	}
+}
+
+func TestCheckerCheckTimeout(t *testing.T) {
+	done := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	client := server.Client()
+	client.Timeout = 50 * time.Millisecond
+
+	health := NewChecker(client).Check(context.Background(), server.URL)
+
+	if health.Status != HealthBroken {
+		t.Errorf("got %s; want %s", health.Status, HealthBroken)
+	}
+
+	if health.StatusCode != 0 || health.Error == "" {
+		t.Errorf("got status code %d and error %q; want no status code and an error", health.StatusCode, health.Error)
+	}
+}
+
+func TestCheckerCheckUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	health := NewChecker(server.Client()).Check(context.Background(), url)
+
+	if health.Status != HealthBroken || health.Error == "" {
+		t.Errorf("got %+v; want a broken link with an error", health)
+	}
+}
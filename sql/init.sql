@@ -4,6 +4,14 @@ CREATE TABLE IF NOT EXISTS movies (
     year integer NOT NULL,
     runtime integer NOT NULL,
     genres text[] NOT NULL,
+    tags text[] NOT NULL DEFAULT '{}',
+    imdb_id text UNIQUE,
+    imdb_rating real,
+    imdb_votes integer,
+    status text NOT NULL DEFAULT 'published',
+    publish_at timestamp(0) with time zone,
+    budget_amount bigint,
+    budget_currency text,
     version integer NOT NULL DEFAULT 1,
     created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
 );
@@ -11,9 +19,193 @@ CREATE TABLE IF NOT EXISTS movies (
 ALTER TABLE movies ADD CONSTRAINT movies_runtime_check CHECK (runtime>=0);
 ALTER TABLE movies ADD CONSTRAINT movies_year_check CHECK (year BETWEEN 1888 and date_part('year',now()));
 ALTER TABLE movies ADD CONSTRAINT genres_length_check CHECK (array_length(genres,1) BETWEEN 1 AND 5);
+ALTER TABLE movies ADD CONSTRAINT movies_status_check CHECK (status IN ('draft', 'review', 'published', 'archived'));
+ALTER TABLE movies ADD CONSTRAINT movies_budget_check CHECK ((budget_amount IS NULL) = (budget_currency IS NULL) AND budget_amount >= 0);
 
 CREATE INDEX IF NOT EXISTS movie_title_idx ON movies USING GIN (to_tsvector('simple', title));
 CREATE INDEX IF NOT EXISTS movie_genres_idx ON movies USING GIN (genres);
+CREATE INDEX IF NOT EXISTS movie_tags_idx ON movies USING GIN (tags);
+CREATE INDEX IF NOT EXISTS movie_publish_at_idx ON movies (publish_at) WHERE status = 'review';
+CREATE INDEX IF NOT EXISTS movie_normalized_title_idx ON movies (lower(regexp_replace(title, '[^[:alnum:]]+', '', 'g')), year);
+
+CREATE TABLE IF NOT EXISTS movie_redirects (
+    old_id bigint PRIMARY KEY,
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS movie_view_buckets (
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    bucket timestamp(0) with time zone NOT NULL,
+    views bigint NOT NULL DEFAULT 0,
+    hits bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (movie_id, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS movie_view_buckets_bucket_idx ON movie_view_buckets (bucket);
+
+CREATE TABLE IF NOT EXISTS videos (
+    id bigserial PRIMARY KEY,
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    kind text NOT NULL,
+    provider text NOT NULL,
+    url text NOT NULL,
+    language text NOT NULL,
+    duration integer NOT NULL,
+    official bool NOT NULL DEFAULT false,
+    health_status text NOT NULL DEFAULT 'unknown',
+    health_code integer,
+    health_error text NOT NULL DEFAULT '',
+    health_checked_at timestamp(0) with time zone,
+    version integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (movie_id, url)
+);
+
+ALTER TABLE videos ADD CONSTRAINT videos_kind_check CHECK (kind IN ('trailer', 'clip'));
+ALTER TABLE videos ADD CONSTRAINT videos_duration_check CHECK (duration > 0);
+
+CREATE TABLE IF NOT EXISTS box_office (
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    region text NOT NULL,
+    gross_amount bigint NOT NULL,
+    currency text NOT NULL,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (movie_id, region)
+);
+
+ALTER TABLE box_office ADD CONSTRAINT box_office_gross_amount_check CHECK (gross_amount >= 0);
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency text PRIMARY KEY,
+    rate numeric(20, 10) NOT NULL,
+    exponent smallint NOT NULL,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE exchange_rates ADD CONSTRAINT exchange_rates_rate_check CHECK (rate > 0);
+
+-- Worldwide gross in USD cents of every movie with box office figures, so
+-- that filters on movies can be applied before it is aggregated. Grosses in a
+-- currency without an exchange rate are left out and flagged through complete.
+CREATE OR REPLACE VIEW movie_gross_usd AS
+SELECT box_office.movie_id,
+    COALESCE(SUM(CASE
+        WHEN box_office.currency = 'USD' THEN box_office.gross_amount
+        ELSE round(box_office.gross_amount * exchange_rates.rate * 100 / power(10::numeric, exchange_rates.exponent))
+    END), 0)::bigint AS gross_usd,
+    bool_and(box_office.currency = 'USD' OR exchange_rates.currency IS NOT NULL) AS complete
+FROM box_office
+LEFT JOIN exchange_rates ON exchange_rates.currency = box_office.currency
+GROUP BY box_office.movie_id;
+
+CREATE TABLE IF NOT EXISTS award_events (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    year integer NOT NULL,
+    held_on date,
+    version integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (name, year)
+);
+
+CREATE TABLE IF NOT EXISTS award_categories (
+    id bigserial PRIMARY KEY,
+    event_id bigint NOT NULL REFERENCES award_events ON DELETE CASCADE,
+    name text NOT NULL,
+    UNIQUE (event_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS award_nominations (
+    id bigserial PRIMARY KEY,
+    category_id bigint NOT NULL REFERENCES award_categories ON DELETE CASCADE,
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    nominee text NOT NULL DEFAULT '',
+    won bool NOT NULL DEFAULT false,
+    UNIQUE (category_id, movie_id, nominee)
+);
+
+CREATE INDEX IF NOT EXISTS award_nominations_movie_id_idx ON award_nominations (movie_id);
+
+CREATE TABLE IF NOT EXISTS series (
+    id bigserial PRIMARY KEY,
+    title text NOT NULL,
+    start_year integer NOT NULL,
+    end_year integer,
+    genres text[] NOT NULL,
+    version integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE series ADD CONSTRAINT series_year_check CHECK (end_year IS NULL OR end_year >= start_year);
+ALTER TABLE series ADD CONSTRAINT series_genres_length_check CHECK (array_length(genres,1) BETWEEN 1 AND 5);
+
+CREATE INDEX IF NOT EXISTS series_title_idx ON series USING GIN (to_tsvector('simple', title));
+CREATE INDEX IF NOT EXISTS series_genres_idx ON series USING GIN (genres);
+
+CREATE TABLE IF NOT EXISTS seasons (
+    id bigserial PRIMARY KEY,
+    series_id bigint NOT NULL REFERENCES series ON DELETE CASCADE,
+    number integer NOT NULL,
+    title text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (series_id, number)
+);
+
+CREATE TABLE IF NOT EXISTS episodes (
+    id bigserial PRIMARY KEY,
+    season_id bigint NOT NULL REFERENCES seasons ON DELETE CASCADE,
+    number integer NOT NULL,
+    title text NOT NULL,
+    runtime integer NOT NULL,
+    air_date date,
+    version integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (season_id, number)
+);
+
+ALTER TABLE episodes ADD CONSTRAINT episodes_runtime_check CHECK (runtime>=0);
+
+CREATE TABLE IF NOT EXISTS cinemas (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    address text NOT NULL,
+    city text NOT NULL,
+    country text NOT NULL,
+    timezone text NOT NULL,
+    version integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS cinemas_name_idx ON cinemas USING GIN (to_tsvector('simple', name));
+CREATE INDEX IF NOT EXISTS cinemas_city_idx ON cinemas (lower(city));
+
+CREATE TABLE IF NOT EXISTS screens (
+    id bigserial PRIMARY KEY,
+    cinema_id bigint NOT NULL REFERENCES cinemas ON DELETE CASCADE,
+    name text NOT NULL,
+    seat_map jsonb NOT NULL,
+    capacity integer NOT NULL,
+    version integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (cinema_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS showtimes (
+    id bigserial PRIMARY KEY,
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    screen_id bigint NOT NULL REFERENCES screens ON DELETE CASCADE,
+    starts_at timestamp(0) with time zone NOT NULL,
+    ends_at timestamp(0) with time zone NOT NULL,
+    version integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE showtimes ADD CONSTRAINT showtimes_ends_at_check CHECK (ends_at > starts_at);
+
+CREATE INDEX IF NOT EXISTS showtimes_screen_id_starts_at_idx ON showtimes (screen_id, starts_at);
+CREATE INDEX IF NOT EXISTS showtimes_movie_id_starts_at_idx ON showtimes (movie_id, starts_at);
 
 CREATE TABLE IF NOT EXISTS users (
     id bigserial PRIMARY KEY,
@@ -25,11 +217,141 @@ CREATE TABLE IF NOT EXISTS users (
     version integer NOT NULL DEFAULT 1
 );
 
+ALTER TABLE movies ADD COLUMN created_by bigint REFERENCES users ON DELETE SET NULL;
+ALTER TABLE movies ADD COLUMN updated_by bigint REFERENCES users ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS movie_created_by_idx ON movies (created_by);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id bigserial PRIMARY KEY,
+    name text UNIQUE NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+-- Votes do not cascade from users, since a cascade would leave the
+-- denormalized movies.tags column stale. A user's votes have to be withdrawn,
+-- which refreshes it, before the user can be deleted.
+CREATE TABLE IF NOT EXISTS movie_tags (
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    tag_id bigint NOT NULL REFERENCES tags ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (movie_id, tag_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS movie_tags_tag_id_idx ON movie_tags (tag_id);
+CREATE INDEX IF NOT EXISTS movie_tags_user_id_idx ON movie_tags (user_id);
+
+CREATE TABLE IF NOT EXISTS subtitles (
+    id bigserial PRIMARY KEY,
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    language text NOT NULL,
+    cue_count integer NOT NULL,
+    blob_key text NOT NULL,
+    text text NOT NULL,
+    created_by bigint REFERENCES users ON DELETE SET NULL,
+    version integer NOT NULL DEFAULT 1,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (movie_id, language)
+);
+
+CREATE INDEX IF NOT EXISTS subtitles_text_idx ON subtitles USING GIN (to_tsvector('simple', text));
+
+CREATE TABLE IF NOT EXISTS reservations (
+    id bigserial PRIMARY KEY,
+    showtime_id bigint NOT NULL REFERENCES showtimes ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    status text NOT NULL,
+    expires_at timestamp(0) with time zone,
+    version integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE reservations ADD CONSTRAINT reservations_status_check CHECK (status IN ('held', 'confirmed', 'cancelled', 'expired'));
+
+CREATE INDEX IF NOT EXISTS reservations_expires_at_idx ON reservations (expires_at) WHERE status = 'held';
+
+CREATE TABLE IF NOT EXISTS reservation_seats (
+    showtime_id bigint NOT NULL REFERENCES showtimes ON DELETE CASCADE,
+    seat text NOT NULL,
+    reservation_id bigint NOT NULL REFERENCES reservations ON DELETE CASCADE,
+    PRIMARY KEY (showtime_id, seat)
+);
+
+CREATE INDEX IF NOT EXISTS reservation_seats_reservation_id_idx ON reservation_seats (reservation_id);
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+	id text PRIMARY KEY,
+	secret_hash bytea NOT NULL,
+	name text NOT NULL,
+	user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+	permissions text[] NOT NULL,
+	created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
 CREATE TABLE IF NOT EXISTS tokens (
 	hash bytea PRIMARY KEY,
+	id bigserial UNIQUE,
 	user_id bigint NOT NULL REFERENCES users on DELETE CASCADE,
 	expiry timestamp(0) with time zone NOT NULL,
-	scope text NOT NULL
+	scope text NOT NULL,
+	created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+	last_used_at timestamp(0) with time zone,
+	ip text NOT NULL DEFAULT '',
+	user_agent text NOT NULL DEFAULT '',
+	family bytea,
+	used_at timestamp(0) with time zone,
+	permissions text[],
+	client_id text REFERENCES oauth_clients ON DELETE CASCADE,
+	attempts integer NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS tokens_user_id_scope_idx ON tokens (user_id, scope);
+CREATE INDEX IF NOT EXISTS tokens_family_idx ON tokens (family);
+CREATE INDEX IF NOT EXISTS tokens_client_id_idx ON tokens (client_id) WHERE client_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS api_keys (
+	id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+	name text NOT NULL,
+	prefix text NOT NULL,
+	hash bytea NOT NULL UNIQUE,
+	permissions text[] NOT NULL,
+	allowed_ips text[] NOT NULL DEFAULT '{}',
+	expiry timestamp(0) with time zone,
+	last_used_at timestamp(0) with time zone,
+	created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
+
+CREATE TABLE IF NOT EXISTS login_failures (
+	key text PRIMARY KEY,
+	failures integer NOT NULL,
+	last_failure_at timestamp(0) with time zone NOT NULL,
+	locked_until timestamp(0) with time zone
+);
+
+CREATE TABLE IF NOT EXISTS users_totp (
+	user_id bigint PRIMARY KEY REFERENCES users ON DELETE CASCADE,
+	secret text NOT NULL,
+	confirmed_at timestamp(0) with time zone,
+	last_step bigint NOT NULL DEFAULT 0,
+	created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS users_recovery_codes (
+	user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+	hash bytea NOT NULL,
+	used_at timestamp(0) with time zone,
+	PRIMARY KEY (user_id, hash)
+);
+
+CREATE TABLE IF NOT EXISTS signing_keys (
+	id text PRIMARY KEY,
+	algorithm text NOT NULL,
+	private_key bytea NOT NULL,
+	created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
 );
 
 CREATE TABLE IF NOT EXISTS permissions (
@@ -43,4 +365,4 @@ CREATE TABLE IF NOT EXISTS users_permissions (
     PRIMARY KEY (user_id, permission_id)
 );
 
-INSERT INTO permissions (code) VALUES('movies:read'),('movies:write');
\ No newline at end of file
+INSERT INTO permissions (code) VALUES('movies:read'),('movies:write'),('movies:admin'),('movies:review'),('movies:publish'),('cinemas:write'),('boxoffice:write'),('awards:write'),('users:admin');
\ No newline at end of file